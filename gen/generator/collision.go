@@ -0,0 +1,289 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// identSource describes the proto element a package-level Go identifier is
+// generated from.
+type identSource struct {
+	kind string
+	name string
+	file string
+}
+
+func (s identSource) String() string {
+	return fmt.Sprintf("%s %s (%s)", s.kind, s.name, s.file)
+}
+
+// identScope collects the identifiers declared in one Go package, or the fields
+// and methods of one generated type.
+type identScope struct {
+	// owner is the type whose members the scope holds, or empty for the
+	// package-level identifiers.
+	owner  string
+	idents map[string]identSource
+	errs   []string
+}
+
+func newIdentScope(owner string) *identScope {
+	return &identScope{owner: owner, idents: make(map[string]identSource)}
+}
+
+// declare records ident as coming from src. The first declaration wins, every
+// later one is reported as a collision.
+func (p *identScope) declare(ident string, src identSource) {
+	if prev, ok := p.idents[ident]; ok {
+		what := fmt.Sprintf("identifier %q", ident)
+		if p.owner != "" {
+			what = fmt.Sprintf("member %q of %s", ident, p.owner)
+		}
+		p.errs = append(p.errs, fmt.Sprintf("%s is generated for both %s and %s", what, prev, src))
+		return
+	}
+	p.idents[ident] = src
+}
+
+// CheckCollisions reports package-level identifiers that the triple stubs would
+// declare more than once in a Go package, and members they would declare more
+// than once on a generated type. Files are grouped by the Go package they are
+// generated into, and the identifiers protoc-gen-go declares for their
+// messages, enums and extensions are taken into account as well when the stubs
+// share a package with them.
+//
+// Only the packages stubs are generated into in this run are checked. Other
+// files in such a package take part, since their stubs may come from another
+// run, but their options are not validated: that is up to the run generating
+// them.
+func CheckCollisions(gen *protogen.Plugin) error {
+	targets := make(map[protogen.GoImportPath]bool)
+	for _, file := range gen.Files {
+		if file.Generate && len(file.Services) > 0 {
+			_, importPath := GoPackage(file)
+			targets[importPath] = true
+		}
+	}
+
+	scopes := make(map[protogen.GoImportPath]*identScope)
+	var order []protogen.GoImportPath
+	scopeOf := func(importPath protogen.GoImportPath) *identScope {
+		scope, ok := scopes[importPath]
+		if !ok {
+			scope = newIdentScope("")
+			scopes[importPath] = scope
+			order = append(order, importPath)
+		}
+		return scope
+	}
+
+	// protoc-gen-go output first, so that its names keep priority in the report.
+	for _, file := range gen.Files {
+		if targets[file.GoImportPath] {
+			declareProtoIdents(scopeOf(file.GoImportPath), file)
+		}
+	}
+	var memberErrs []string
+	for _, file := range gen.Files {
+		_, importPath := GoPackage(file)
+		if len(file.Services) == 0 || !targets[importPath] {
+			continue
+		}
+		tripleGo, err := processProtoFile(gen, file, file.Generate)
+		if err != nil {
+			return err
+		}
+		scope := scopeOf(importPath)
+		for _, s := range tripleGo.Services {
+			src := identSource{kind: "service", name: s.FullName, file: tripleGo.Source}
+			for _, ident := range tripleIdents(s) {
+				scope.declare(ident, src)
+			}
+			if file.Generate {
+				for _, err := range checkMembers(s, tripleGo.Source) {
+					memberErrs = append(memberErrs, fmt.Sprintf("package %s: %s", importPath, err))
+				}
+			}
+		}
+	}
+
+	var errs []string
+	for _, importPath := range order {
+		for _, err := range scopes[importPath].errs {
+			errs = append(errs, fmt.Sprintf("package %s: %s", importPath, err))
+		}
+	}
+	errs = append(errs, memberErrs...)
+	if len(errs) > 0 {
+		return fmt.Errorf("generated identifiers collide:\n%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// typeMembers lists the fields and methods generated on a type whose members
+// are named after the RPCs of a service.
+type typeMembers struct {
+	typeName string
+	members  []member
+}
+
+// member is a field or method of a generated type. method is the RPC it is
+// named after, or nil for members with a fixed name.
+type member struct {
+	name   string
+	method *Method
+}
+
+// checkMembers reports the members the stubs of s would declare more than once
+// on one of their types.
+func checkMembers(s Service, source string) []string {
+	var errs []string
+	for _, t := range append(mockMembers(s), inProcessMembers(s)...) {
+		scope := newIdentScope(t.typeName)
+		for _, m := range t.members {
+			src := identSource{kind: "type", name: t.typeName, file: source}
+			if m.method != nil {
+				src = identSource{kind: "method", name: s.FullName + "." + m.method.MethodName, file: source}
+			}
+			scope.declare(m.name, src)
+		}
+		errs = append(errs, scope.errs...)
+	}
+	return errs
+}
+
+// declareProtoIdents records the package-level identifiers protoc-gen-go
+// generates for file.
+func declareProtoIdents(scope *identScope, file *protogen.File) {
+	path := file.Desc.Path()
+	scope.declare(file.GoDescriptorIdent.GoName, identSource{kind: "file", name: path, file: path})
+	var declareEnums func(enums []*protogen.Enum)
+	declareEnums = func(enums []*protogen.Enum) {
+		for _, enum := range enums {
+			src := identSource{kind: "enum", name: string(enum.Desc.FullName()), file: path}
+			scope.declare(enum.GoIdent.GoName, src)
+			scope.declare(enum.GoIdent.GoName+"_name", src)
+			scope.declare(enum.GoIdent.GoName+"_value", src)
+			for _, value := range enum.Values {
+				scope.declare(value.GoIdent.GoName, identSource{kind: "enum value", name: string(value.Desc.FullName()), file: path})
+			}
+		}
+	}
+	var declareMessages func(messages []*protogen.Message)
+	declareMessages = func(messages []*protogen.Message) {
+		for _, message := range messages {
+			if message.Desc.IsMapEntry() {
+				continue
+			}
+			scope.declare(message.GoIdent.GoName, identSource{kind: "message", name: string(message.Desc.FullName()), file: path})
+			for _, field := range message.Fields {
+				if field.Oneof != nil && !field.Oneof.Desc.IsSynthetic() {
+					scope.declare(field.GoIdent.GoName, identSource{kind: "oneof field", name: string(field.Desc.FullName()), file: path})
+				}
+			}
+			declareEnums(message.Enums)
+			declareMessages(message.Messages)
+			for _, ext := range message.Extensions {
+				scope.declare("E_"+ext.GoIdent.GoName, identSource{kind: "extension", name: string(ext.Desc.FullName()), file: path})
+			}
+		}
+	}
+	declareEnums(file.Enums)
+	declareMessages(file.Messages)
+	for _, ext := range file.Extensions {
+		scope.declare("E_"+ext.GoIdent.GoName, identSource{kind: "extension", name: string(ext.Desc.FullName()), file: path})
+	}
+}
+
+// tripleIdents lists the package-level identifiers the emitter and the mocks
+// declare for s. TestTripleIdents compares it with the generated code.
+func tripleIdents(s Service) []string {
+	idents := []string{s.GoName + "Name", s.GoName + "Descriptor"}
+	if s.InterfaceName != s.FullName {
+		idents = append(idents, s.GoName+"InterfaceName")
+	}
+	idents = append(idents, validateIdents(s)...)
+	idents = append(idents, errorIdents(s)...)
+	for _, m := range s.Methods {
+		idents = append(idents, s.GoName+m.GoName+"Procedure")
+	}
+	if *Mocks {
+		for _, m := range s.Methods {
+			idents = append(idents, mockCall(s, m))
+		}
+	}
+	if *Client {
+		idents = append(idents,
+			s.GoName,
+			s.GoName+"Impl",
+			s.GoName+"_ClientInfo",
+			"New"+s.GoName,
+			"SetConsumer"+s.GoName,
+		)
+		for _, m := range s.Methods {
+			if m.isStream() {
+				idents = append(idents, s.GoName+"_"+m.GoName+"Client", s.GoName+m.GoName+"Client")
+			}
+			if m.Deprecated && *WarnDeprecated {
+				idents = append(idents, deprecationWarningIdent(s, m))
+			}
+		}
+		if *Mocks {
+			idents = append(idents, "Mock"+s.GoName)
+			for _, m := range s.Methods {
+				if m.isStream() {
+					idents = append(idents, "Fake"+s.GoName+m.GoName+"Client")
+				}
+			}
+		}
+	}
+	if *Server {
+		idents = append(idents,
+			s.GoName+"Handler",
+			"Unimplemented"+s.GoName+"Handler",
+			s.GoName+"_ServiceInfo",
+			"Register"+s.GoName+"Handler",
+			"SetProvider"+s.GoName,
+		)
+		for _, m := range s.Methods {
+			if m.isStream() {
+				idents = append(idents, s.GoName+"_"+m.GoName+"Server", s.GoName+m.GoName+"Server")
+			}
+		}
+		if *Mocks {
+			idents = append(idents, "Mock"+s.GoName+"Handler")
+			if hasStream(s) {
+				idents = append(idents, fakeHandlerConn(s))
+			}
+			for _, m := range s.Methods {
+				if m.isStream() {
+					idents = append(idents, "Fake"+s.GoName+m.GoName+"Server")
+				}
+			}
+		}
+		idents = append(idents, restIdents(s)...)
+	}
+	idents = append(idents, inProcessIdents(s)...)
+	return idents
+}
@@ -0,0 +1,284 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+	"testing"
+)
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestCheckCollisions(t *testing.T) {
+	tests := []struct {
+		name   string
+		params []string
+		file   string
+		// errs are substrings of the error, which is nil when there are none.
+		errs []string
+	}{
+		{
+			name: "no collision",
+			file: `service { name: "GreetService" ` + rpc("Greet") + ` }`,
+		},
+		{
+			name: "procedure of another service",
+			file: `
+				service { name: "Greet" ` + rpc("AServiceName") + ` }
+				service { name: "GreetA" ` + rpc("ServiceName") + ` }`,
+			errs: []string{`identifier "GreetAServiceNameProcedure" is generated for both service greet.Greet (greet.proto) and service greet.GreetA (greet.proto)`},
+		},
+		{
+			name: "message named like a stub",
+			file: `
+				message_type { name: "GreetServiceImpl" }
+				service { name: "GreetService" ` + rpc("Greet") + ` }`,
+			errs: []string{`identifier "GreetServiceImpl" is generated for both message greet.GreetServiceImpl (greet.proto) and service greet.GreetService (greet.proto)`},
+		},
+		{
+			name:   "message named like a stub of the disabled client",
+			params: []string{"client=false"},
+			file: `
+				message_type { name: "GreetServiceImpl" }
+				service { name: "GreetService" ` + rpc("Greet") + ` }`,
+		},
+		{
+			name:   "mock members",
+			params: []string{"mocks=true"},
+			file:   `service { name: "GreetService" ` + rpc("Greet") + ` ` + rpc("GreetCalls") + ` }`,
+			errs: []string{
+				`member "GreetCalls" of MockGreetService is generated for both method greet.GreetService.Greet (greet.proto) and method greet.GreetService.GreetCalls (greet.proto)`,
+				`member "GreetCalls" of MockGreetServiceHandler is generated for both method greet.GreetService.Greet (greet.proto) and method greet.GreetService.GreetCalls (greet.proto)`,
+			},
+		},
+		{
+			name: "mock members without mocks",
+			file: `service { name: "GreetService" ` + rpc("Greet") + ` ` + rpc("GreetCalls") + ` }`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setParams(t, tt.params...)
+			plugin := newPlugin(t, newRequest(t, `
+				name: "greet.proto"
+				package: "greet"
+				options { go_package: "example.com/greet" }
+				`+greetMessages+tt.file))
+			err := CheckCollisions(plugin)
+			if len(tt.errs) == 0 {
+				if err != nil {
+					t.Fatalf("CheckCollisions: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("CheckCollisions succeeded, want a collision")
+			}
+			for _, want := range tt.errs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("CheckCollisions error\n%v\ndoes not contain\n%s", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckCollisionsAcrossFiles(t *testing.T) {
+	common := `
+		name: "common.proto"
+		package: "greet"
+		options { go_package: "example.com/greet" }
+		` + greetMessages + `
+		service {
+			name: "CommonService"
+			method {
+				name: "Get"
+				input_type: ".greet.GreetRequest"
+				output_type: ".greet.GreetResponse"
+				options { [dubbo.method] { timeout: "soon" } }
+			}
+		}`
+	greet := func(goPackage, service string) string {
+		return `
+			name: "greet.proto"
+			package: "greet"
+			dependency: "common.proto"
+			options { go_package: "` + goPackage + `" }
+			service { name: "` + service + `" ` + rpc("Greet") + ` }`
+	}
+	tests := []struct {
+		name string
+		// generateCommon also generates common.proto, besides greet.proto.
+		generateCommon bool
+		greet          string
+		err            string
+	}{
+		{
+			name:  "invalid options of a dependency",
+			greet: greet("example.com/greet", "GreetService"),
+		},
+		{
+			name:  "collision with a dependency in the package",
+			greet: greet("example.com/greet", "Common_service"),
+			err:   `identifier "CommonServiceName" is generated for both service greet.CommonService (common.proto) and service greet.Common_service (greet.proto)`,
+		},
+		{
+			name:  "dependency in another package",
+			greet: greet("example.com/greet/v2", "Common_service"),
+		},
+		{
+			name:           "invalid options of a generated file",
+			generateCommon: true,
+			greet:          greet("example.com/greet", "GreetService"),
+			err:            `dubbo option of greet.CommonService.Get: timeout "soon" is not a positive duration`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setParams(t)
+			req := newRequest(t, common, tt.greet)
+			if !tt.generateCommon {
+				req.FileToGenerate = []string{"greet.proto"}
+			}
+			err := CheckCollisions(newPlugin(t, req))
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("CheckCollisions: %v", err)
+			case tt.err != "" && err == nil:
+				t.Fatalf("CheckCollisions succeeded, want %s", tt.err)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Fatalf("CheckCollisions error\n%v\ndoes not contain\n%s", err, tt.err)
+			}
+		})
+	}
+}
+
+// TestTripleIdents checks tripleIdents against the package-level declarations
+// of the generated stubs and mocks, with every feature declaring some turned on.
+func TestTripleIdents(t *testing.T) {
+	features := []string{"mocks=true", "validate=all", "warn_deprecated=true", "interface_name=java", "iterators=true"}
+	for _, params := range [][]string{
+		append([]string{"inprocess=true"}, features...),
+		append([]string{"client=false"}, features...),
+		append([]string{"server=false"}, features...),
+	} {
+		t.Run(strings.Join(params[:1], ","), func(t *testing.T) {
+			setParams(t, params...)
+			req := newRequest(t, `
+				name: "greet.proto"
+				package: "greet"
+				options { go_package: "example.com/greet" java_package: "com.acme.greet" }
+				`+greetMessages+`
+				message_type {
+					name: "CheckedRequest"
+					field { name: "name" number: 1 type: TYPE_STRING json_name: "name" }
+				}
+				message_type { name: "NotFound" }
+				`+greetStreams+`
+				service {
+					name: "LibraryService"
+					method { name: "Check" input_type: ".greet.CheckedRequest" output_type: ".greet.GreetResponse" }
+					method {
+						name: "Old" input_type: ".greet.GreetRequest" output_type: ".greet.GreetResponse"
+						options { deprecated: true }
+					}
+					`+erring("Find", "greet.NotFound")+`
+				}`)
+			file := req.ProtoFile[0]
+			field := file.MessageType[2].Field[0]
+			field.Options = &descriptorpb.FieldOptions{}
+			field.Options.ProtoReflect().SetUnknown(optionWith(1159))
+			check := file.Service[1].Method[0]
+			check.Options = &descriptorpb.MethodOptions{}
+			rule := protowire.AppendTag(nil, httpRuleField, protowire.BytesType)
+			rule = protowire.AppendBytes(rule, httpRule(httpRuleGet, "/v1/{name}"))
+			check.Options.ProtoReflect().SetUnknown(rule)
+			plugin := newPlugin(t, req)
+			if err := CheckCollisions(plugin); err != nil {
+				t.Fatal(err)
+			}
+
+			tripleGo, err := ProcessProtoFile(plugin, plugin.FilesByPath["greet.proto"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			tripleGo.Package = "greet"
+			g := plugin.NewGeneratedFile("greet.triple_mock.go", "example.com/greet")
+			if err := GenMockFile(g, tripleGo); err != nil {
+				t.Fatal(err)
+			}
+			mocks, err := g.Content()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := append(declaredIdents(t, generate(t, plugin, "greet.proto")), declaredIdents(t, string(mocks))...)
+			var want []string
+			for _, s := range tripleGo.Services {
+				want = append(want, tripleIdents(s)...)
+			}
+			sort.Strings(got)
+			sort.Strings(want)
+			if strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("generated stubs declare\n%v\ntripleIdents lists\n%v", got, want)
+			}
+		})
+	}
+}
+
+// declaredIdents returns the names of the package-level declarations of src,
+// but for the blank identifier.
+func declaredIdents(t *testing.T, src string) []string {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var idents []string
+	add := func(name *ast.Ident) {
+		if name.Name != "_" {
+			idents = append(idents, name.Name)
+		}
+	}
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil {
+				add(decl.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					add(spec.Name)
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						add(name)
+					}
+				}
+			}
+		}
+	}
+	return idents
+}
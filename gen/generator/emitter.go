@@ -0,0 +1,770 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/dubbogo/protoc-gen-go-triple/v3/util"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+const (
+	contextPackage        = protogen.GoImportPath("context")
+	errorsPackage         = protogen.GoImportPath("errors")
+	httpPackage           = protogen.GoImportPath("net/http")
+	dubboPackage          = protogen.GoImportPath("dubbo.apache.org/dubbo-go/v3")
+	clientPackage         = protogen.GoImportPath("dubbo.apache.org/dubbo-go/v3/client")
+	commonPackage         = protogen.GoImportPath("dubbo.apache.org/dubbo-go/v3/common")
+	constantPackage       = protogen.GoImportPath("dubbo.apache.org/dubbo-go/v3/common/constant")
+	tripleProtocolPackage = protogen.GoImportPath("dubbo.apache.org/dubbo-go/v3/protocol/triple/triple_protocol")
+	serverPackage         = protogen.GoImportPath("dubbo.apache.org/dubbo-go/v3/server")
+	loggerPackage         = protogen.GoImportPath("github.com/dubbogo/gost/log/logger")
+	protoreflectPackage   = protogen.GoImportPath("google.golang.org/protobuf/reflect/protoreflect")
+	syncPackage           = protogen.GoImportPath("sync")
+)
+
+const deprecationComment = "// Deprecated: Do not use."
+
+// Keys of the descriptor accessors in the Meta of ServiceInfo and MethodInfo.
+// The values are a func() protoreflect.ServiceDescriptor and a
+// func() protoreflect.MethodDescriptor respectively, whose Input and Output give
+// the message descriptors of the method.
+const (
+	serviceDescriptorMeta = "ServiceDescriptor"
+	methodDescriptorMeta  = "MethodDescriptor"
+)
+
+// generate writes the whole triple file. The sections follow the layout of the
+// generated file: names, type checks, client side and finally server side. The
+// client and server sides are left out when they are disabled, and protogen only
+// imports the packages the remaining sections refer to.
+func (gen *Generator) generate(g *protogen.GeneratedFile, t TripleGo) {
+	genPreamble(g, t)
+	genTotal(g, t)
+	genDescriptors(g, t)
+	genTypeCheck(g, t)
+	genValidate(g, t)
+	if *Client {
+		genClientInterface(g, t)
+		genClientInterfaceImpl(g, t)
+		genClientImpl(g, t)
+		genMethodInfo(g, t)
+		genErrorExtractors(g, t)
+	}
+	if *Server {
+		genHandler(g, t)
+		genServerImpl(g, t)
+		genServiceInfo(g, t)
+		genErrorConstructors(g, t)
+		genREST(g, t)
+	}
+	if *InProcess {
+		genInProcess(g, t)
+	}
+}
+
+func genPreamble(g *protogen.GeneratedFile, t TripleGo) {
+	g.P("// Code generated by protoc-gen-triple. DO NOT EDIT.")
+	g.P("//")
+	g.P("// Source: ", t.Source)
+	if t.Deprecated {
+		g.P("// ", t.Source, " is a deprecated file.")
+	}
+	g.P("package ", strings.ReplaceAll(t.Package, ".", "_"))
+	g.P()
+}
+
+func genTotal(g *protogen.GeneratedFile, t TripleGo) {
+	g.P("// This is a compile-time assertion to ensure that this generated file and the Triple package")
+	g.P("// are compatible. If you get a compiler error that this constant is not defined, this code was")
+	g.P("// generated with a version of Triple newer than the one compiled into your binary. You can fix the")
+	g.P("// problem by either regenerating this code with an older version of Triple or updating the Triple")
+	g.P("// version compiled into your binary.")
+	g.P("const _ = ", tripleProtocolPackage.Ident("IsAtLeastVersion0_1_0"))
+	for _, s := range t.Services {
+		g.P()
+		g.P("const (")
+		g.P("// ", s.GoName, "Name is the fully-qualified name of the ", s.ServiceName, " service.")
+		if s.Deprecated {
+			g.P("//")
+			g.P(deprecationComment)
+		}
+		g.P(s.GoName, "Name = ", strconv.Quote(s.FullName))
+		if s.InterfaceName != s.FullName {
+			g.P("// ", s.GoName, "InterfaceName is the name the ", s.ServiceName, " service is registered and")
+			g.P("// referenced under in the dubbo registry. Its procedures keep the proto name.")
+			g.P(s.GoName, "InterfaceName = ", strconv.Quote(s.InterfaceName))
+		}
+		g.P(")")
+		g.P()
+		g.P("// These constants are the fully-qualified names of the RPCs defined in this package. They're")
+		g.P("// exposed at runtime as procedure and as the final two segments of the HTTP route.")
+		g.P("//")
+		g.P("// Note that these are different from the fully-qualified method names used by")
+		g.P("// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to")
+		g.P("// reflection-formatted method names, remove the leading slash and convert the remaining slash to a")
+		g.P("// period.")
+		g.P("const (")
+		for _, m := range s.Methods {
+			g.P("// ", s.GoName, m.GoName, "Procedure is the fully-qualified name of the ", s.ServiceName, "'s ", m.MethodName, " RPC.")
+			if m.Deprecated {
+				g.P("//")
+				g.P(deprecationComment)
+			}
+			g.P(s.GoName, m.GoName, "Procedure = ", strconv.Quote("/"+s.FullName+"/"+m.MethodName))
+		}
+		g.P(")")
+	}
+	g.P()
+}
+
+// genDescriptors writes an accessor for the descriptor of every service. The
+// File_* variable is only set by the init function of the .pb.go file, which may
+// run after the variables of this file are initialized, so the lookup is done
+// on each call rather than stored in a variable.
+func genDescriptors(g *protogen.GeneratedFile, t TripleGo) {
+	for _, s := range t.Services {
+		g.P("// ", s.GoName, "Descriptor returns the descriptor of the ", s.FullName, " service. It must not be")
+		g.P("// called before the package has been initialized.")
+		g.P("func ", s.GoName, "Descriptor() ", protoreflectPackage.Ident("ServiceDescriptor"), " {")
+		g.P("return ", t.Descriptor, ".Services().ByName(", strconv.Quote(s.ServiceName), ")")
+		g.P("}")
+		g.P()
+	}
+}
+
+func genTypeCheck(g *protogen.GeneratedFile, t TripleGo) {
+	g.P("var (")
+	for _, s := range t.Services {
+		if *Client {
+			g.P("_ ", s.GoName, " = (*", s.GoName, "Impl)(nil)")
+		}
+		if *Server {
+			g.P("_ ", s.GoName, "Handler = (*Unimplemented", s.GoName, "Handler)(nil)")
+		}
+		for _, m := range s.Methods {
+			if m.isStream() && *Client {
+				g.P("_ ", s.GoName, "_", m.GoName, "Client = (*", s.GoName, m.GoName, "Client)(nil)")
+			}
+		}
+		for _, m := range s.Methods {
+			if m.isStream() && *Server {
+				g.P("_ ", s.GoName, "_", m.GoName, "Server = (*", s.GoName, m.GoName, "Server)(nil)")
+			}
+		}
+	}
+	g.P(")")
+	g.P()
+}
+
+// clientSignature returns the signature of m on the client interface, without
+// the leading method name.
+func clientSignature(g *protogen.GeneratedFile, s Service, m Method) string {
+	sig := "(ctx " + g.QualifiedGoIdent(contextPackage.Ident("Context"))
+	if !m.StreamsRequest {
+		sig += ", req *" + g.QualifiedGoIdent(m.RequestType)
+	}
+	sig += ", opts ..." + g.QualifiedGoIdent(clientPackage.Ident("CallOption")) + ") "
+	if m.isStream() {
+		return sig + "(" + s.GoName + "_" + m.GoName + "Client, error)"
+	}
+	return sig + "(*" + g.QualifiedGoIdent(m.ReturnType) + ", error)"
+}
+
+// handlerSignature returns the signature of m on the handler interface, without
+// the leading method name.
+func handlerSignature(g *protogen.GeneratedFile, s Service, m Method) string {
+	sig := "(" + g.QualifiedGoIdent(contextPackage.Ident("Context")) + ", "
+	if m.StreamsRequest {
+		sig += s.GoName + "_" + m.GoName + "Server"
+	} else {
+		sig += "*" + g.QualifiedGoIdent(m.RequestType)
+		if m.StreamsReturn {
+			sig += ", " + s.GoName + "_" + m.GoName + "Server"
+		}
+	}
+	if m.StreamsReturn {
+		return sig + ") error"
+	}
+	return sig + ") (*" + g.QualifiedGoIdent(m.ReturnType) + ", error)"
+}
+
+func genClientInterface(g *protogen.GeneratedFile, t TripleGo) {
+	for _, s := range t.Services {
+		g.P("// ", s.GoName, " is a client for the ", s.FullName, " service.")
+		genServiceComments(g, s)
+		g.Annotate(s.GoName, s.Location)
+		g.P("type ", s.GoName, " interface {")
+		for _, m := range s.Methods {
+			genMethodComments(g, m)
+			g.Annotate(s.GoName+"."+m.GoName, m.Location)
+			g.P(m.GoName, clientSignature(g, s, m))
+		}
+		g.P("}")
+		g.P()
+	}
+}
+
+func genClientInterfaceImpl(g *protogen.GeneratedFile, t TripleGo) {
+	for _, s := range t.Services {
+		g.P("// New", s.GoName, " constructs a client for the ", s.FullName, " service.")
+		genServiceComments(g, s)
+		g.Annotate("New"+s.GoName, s.Location)
+		g.P("func New", s.GoName, "(cli *", clientPackage.Ident("Client"), ", opts ...", clientPackage.Ident("ReferenceOption"), ") (", s.GoName, ", error) {")
+		genReferenceDefaults(g, s)
+		g.P("conn, err := cli.DialWithInfo(", interfaceNameExpr(s), ", &", s.GoName, "_ClientInfo, opts...)")
+		g.P("if err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return &", s.GoName, "Impl{")
+		g.P("conn: conn,")
+		g.P("}, nil")
+		g.P("}")
+		g.P()
+		if s.Deprecated {
+			g.P(deprecationComment)
+		}
+		g.P("func SetConsumer", s.GoName, "(srv ", commonPackage.Ident("RPCService"), ") {")
+		g.P(dubboPackage.Ident("SetConsumerServiceWithInfo"), "(srv, &", s.GoName, "_ClientInfo)")
+		g.P("}")
+		g.P()
+		g.P("// ", s.GoName, "Impl implements ", s.GoName, ".")
+		g.P("type ", s.GoName, "Impl struct {")
+		g.P("conn *", clientPackage.Ident("Connection"))
+		if validateClient() && hasValidation(s) {
+			g.P("validate func(", protoPackage.Ident("Message"), ") error")
+		}
+		g.P("}")
+		g.P()
+		for _, m := range s.Methods {
+			warnOnce := deprecationWarningIdent(s, m)
+			if m.Deprecated && *WarnDeprecated {
+				g.P("var ", warnOnce, " ", syncPackage.Ident("Once"))
+				g.P()
+			}
+			if m.Deprecated {
+				g.P(deprecationComment)
+			}
+			g.P("func (c *", s.GoName, "Impl) ", m.GoName, clientSignature(g, s, m), " {")
+			if m.Deprecated && *WarnDeprecated {
+				g.P(warnOnce, ".Do(func() {")
+				g.P(loggerPackage.Ident("Warnf"), "(\"%s is deprecated\", ", s.GoName, m.GoName, "Procedure)")
+				g.P("})")
+			}
+			if validateClient() && m.validates() {
+				genValidateCall(g, s, "c.validate", "req")
+			}
+			genCallDefaults(g, s, m)
+			switch {
+			case m.StreamsRequest && m.StreamsReturn:
+				genClientStreamCall(g, s, m, "CallBidiStream(ctx, ", "BidiStreamForClient")
+			case m.StreamsRequest:
+				genClientStreamCall(g, s, m, "CallClientStream(ctx, ", "ClientStreamForClient")
+			case m.StreamsReturn:
+				genClientStreamCall(g, s, m, "CallServerStream(ctx, req, ", "ServerStreamForClient")
+			default:
+				g.P("resp := new(", m.ReturnType, ")")
+				g.P("if err := c.conn.CallUnary(ctx, []interface{}{req}, resp, ", strconv.Quote(m.MethodName), ", opts...); err != nil {")
+				g.P("return nil, err")
+				g.P("}")
+				g.P("return resp, nil")
+			}
+			g.P("}")
+			g.P()
+		}
+	}
+}
+
+func genClientStreamCall(g *protogen.GeneratedFile, s Service, m Method, call, streamType string) {
+	g.P("stream, err := c.conn.", call, strconv.Quote(m.MethodName), ", opts...)")
+	g.P("if err != nil {")
+	g.P("return nil, err")
+	g.P("}")
+	g.P("rawStream := stream.(*", tripleProtocolPackage.Ident(streamType), ")")
+	g.P("return &", s.GoName, m.GoName, "Client{rawStream}, nil")
+}
+
+func genClientImpl(g *protogen.GeneratedFile, t TripleGo) {
+	spec := tripleProtocolPackage.Ident("Spec")
+	peer := tripleProtocolPackage.Ident("Peer")
+	header := httpPackage.Ident("Header")
+	conn := tripleProtocolPackage.Ident("StreamingClientConn")
+	for _, s := range t.Services {
+		for _, m := range s.Methods {
+			iface := s.GoName + "_" + m.GoName + "Client"
+			impl := s.GoName + m.GoName + "Client"
+			switch {
+			case m.StreamsRequest && m.StreamsReturn:
+				g.P("type ", iface, " interface {")
+				g.P("Spec() ", spec)
+				g.P("Peer() ", peer)
+				g.P("Send(*", m.RequestType, ") error")
+				g.P("RequestHeader() ", header)
+				g.P("CloseRequest() error")
+				g.P("Recv() (*", m.ReturnType, ", error)")
+				genAllDecl(g, m.ReturnType)
+				g.P("ResponseHeader() ", header)
+				g.P("ResponseTrailer() ", header)
+				g.P("CloseResponse() error")
+				g.P("}")
+				g.P()
+				g.P("type ", impl, " struct {")
+				g.P("*", tripleProtocolPackage.Ident("BidiStreamForClient"))
+				g.P("}")
+				g.P()
+				g.P("func (cli *", impl, ") Send(msg *", m.RequestType, ") error {")
+				g.P("return cli.BidiStreamForClient.Send(msg)")
+				g.P("}")
+				g.P()
+				g.P("func (cli *", impl, ") Recv() (*", m.ReturnType, ", error) {")
+				g.P("msg := new(", m.ReturnType, ")")
+				g.P("if err := cli.BidiStreamForClient.Receive(msg); err != nil {")
+				g.P("return nil, err")
+				g.P("}")
+				g.P("return msg, nil")
+				g.P("}")
+				g.P()
+				genAll(g, "cli", impl, m.ReturnType, true)
+			case m.StreamsRequest:
+				g.P("type ", iface, " interface {")
+				g.P("Spec() ", spec)
+				g.P("Peer() ", peer)
+				g.P("Send(*", m.RequestType, ") error")
+				g.P("RequestHeader() ", header)
+				g.P("CloseAndRecv() (*", m.ReturnType, ", error)")
+				g.P("Conn() (", conn, ", error)")
+				g.P("}")
+				g.P()
+				g.P("type ", impl, " struct {")
+				g.P("*", tripleProtocolPackage.Ident("ClientStreamForClient"))
+				g.P("}")
+				g.P()
+				g.P("func (cli *", impl, ") Send(msg *", m.RequestType, ") error {")
+				g.P("return cli.ClientStreamForClient.Send(msg)")
+				g.P("}")
+				g.P()
+				g.P("func (cli *", impl, ") CloseAndRecv() (*", m.ReturnType, ", error) {")
+				g.P("msg := new(", m.ReturnType, ")")
+				g.P("resp := ", tripleProtocolPackage.Ident("NewResponse"), "(msg)")
+				g.P("if err := cli.ClientStreamForClient.CloseAndReceive(resp); err != nil {")
+				g.P("return nil, err")
+				g.P("}")
+				g.P("return msg, nil")
+				g.P("}")
+				g.P()
+				g.P("func (cli *", impl, ") Conn() (", conn, ", error) {")
+				g.P("return cli.ClientStreamForClient.Conn()")
+				g.P("}")
+				g.P()
+			case m.StreamsReturn:
+				g.P("type ", iface, " interface {")
+				g.P("Recv() bool")
+				g.P("ResponseHeader() ", header)
+				g.P("ResponseTrailer() ", header)
+				g.P("Msg() *", m.ReturnType)
+				g.P("Err() error")
+				genAllDecl(g, m.ReturnType)
+				g.P("Conn() (", conn, ", error)")
+				g.P("Close() error")
+				g.P("}")
+				g.P()
+				g.P("type ", impl, " struct {")
+				g.P("*", tripleProtocolPackage.Ident("ServerStreamForClient"))
+				g.P("}")
+				g.P()
+				g.P("func (cli *", impl, ") Recv() bool {")
+				g.P("msg := new(", m.ReturnType, ")")
+				g.P("return cli.ServerStreamForClient.Receive(msg)")
+				g.P("}")
+				g.P()
+				g.P("func (cli *", impl, ") Msg() *", m.ReturnType, " {")
+				g.P("msg := cli.ServerStreamForClient.Msg()")
+				g.P("if msg == nil {")
+				g.P("return new(", m.ReturnType, ")")
+				g.P("}")
+				g.P("return msg.(*", m.ReturnType, ")")
+				g.P("}")
+				g.P()
+				genAll(g, "cli", impl, m.ReturnType, false)
+				g.P("func (cli *", impl, ") Conn() (", conn, ", error) {")
+				g.P("return cli.ServerStreamForClient.Conn()")
+				g.P("}")
+				g.P()
+			}
+		}
+	}
+}
+
+func genMethodInfo(g *protogen.GeneratedFile, t TripleGo) {
+	for _, s := range t.Services {
+		names := make([]string, 0, len(s.Methods))
+		for _, m := range s.Methods {
+			names = append(names, strconv.Quote(m.MethodName))
+		}
+		g.P("var ", s.GoName, "_ClientInfo = ", clientPackage.Ident("ClientInfo"), "{")
+		g.P("InterfaceName: ", interfaceNameExpr(s), ",")
+		g.P("MethodNames: []string{", strings.Join(names, ", "), "},")
+		g.P("ConnectionInjectFunc: func(dubboCliRaw interface{}, conn *", clientPackage.Ident("Connection"), ") {")
+		g.P("dubboCli := dubboCliRaw.(*", s.GoName, "Impl)")
+		g.P("dubboCli.conn = conn")
+		g.P("},")
+		if !s.Options.empty() || hasIdempotentMethods(s) {
+			g.P("Meta: map[string]interface{}{")
+			genOptionsMeta(g, s.Options)
+			genClientIdempotencyMeta(g, s)
+			g.P("},")
+		}
+		g.P("}")
+		g.P()
+	}
+}
+
+func genHandler(g *protogen.GeneratedFile, t TripleGo) {
+	for _, s := range t.Services {
+		g.P("// ", s.GoName, "Handler is an implementation of the ", s.FullName, " service.")
+		genServiceComments(g, s)
+		g.Annotate(s.GoName+"Handler", s.Location)
+		g.P("type ", s.GoName, "Handler interface {")
+		for _, m := range s.Methods {
+			genMethodComments(g, m)
+			g.Annotate(s.GoName+"Handler."+m.GoName, m.Location)
+			g.P(m.GoName, handlerSignature(g, s, m))
+		}
+		if *RequireUnimplemented {
+			g.P("mustEmbedUnimplemented", s.GoName, "Handler()")
+		}
+		g.P("}")
+		g.P()
+		g.P("// Register", s.GoName, "Handler registers hdlr as the ", s.FullName, " service of srv.")
+		genServiceComments(g, s)
+		g.Annotate("Register"+s.GoName+"Handler", s.Location)
+		g.P("func Register", s.GoName, "Handler(srv *", serverPackage.Ident("Server"), ", hdlr ", s.GoName, "Handler, opts ...", serverPackage.Ident("ServiceOption"), ") error {")
+		genServiceDefaults(g, s)
+		g.P("return srv.Register(hdlr, &", s.GoName, "_ServiceInfo, opts...)")
+		g.P("}")
+		g.P()
+		if s.Deprecated {
+			g.P(deprecationComment)
+		}
+		g.P("func SetProvider", s.GoName, "(srv ", commonPackage.Ident("RPCService"), ") {")
+		g.P(dubboPackage.Ident("SetProviderServiceWithInfo"), "(srv, &", s.GoName, "_ServiceInfo)")
+		g.P("}")
+		g.P()
+		genUnimplementedHandler(g, t, s)
+	}
+}
+
+func genUnimplementedHandler(g *protogen.GeneratedFile, t TripleGo, s Service) {
+	mustOrShould := "should"
+	if *RequireUnimplemented {
+		mustOrShould = "must"
+	}
+	name := "Unimplemented" + s.GoName + "Handler"
+	g.P("// ", name, " returns CodeUnimplemented from all methods. Implementations of ", s.GoName, "Handler")
+	g.P("// ", mustOrShould, " embed it to stay forward compatible when new RPCs are added to the service.")
+	g.P("type ", name, " struct{}")
+	g.P()
+	for _, m := range s.Methods {
+		g.P("func (", name, ") ", m.GoName, handlerSignature(g, s, m), " {")
+		err := fmt.Sprintf("%s(%s, %s(%q))",
+			g.QualifiedGoIdent(tripleProtocolPackage.Ident("NewError")),
+			g.QualifiedGoIdent(tripleProtocolPackage.Ident("CodeUnimplemented")),
+			g.QualifiedGoIdent(errorsPackage.Ident("New")),
+			s.FullName+"."+m.MethodName+" is not implemented")
+		if m.StreamsReturn {
+			g.P("return ", err)
+		} else {
+			g.P("return nil, ", err)
+		}
+		g.P("}")
+		g.P()
+	}
+	if *RequireUnimplemented {
+		g.P("func (", name, ") mustEmbedUnimplemented", s.GoName, "Handler() {}")
+		g.P()
+	}
+}
+
+func genServerImpl(g *protogen.GeneratedFile, t TripleGo) {
+	spec := tripleProtocolPackage.Ident("Spec")
+	peer := tripleProtocolPackage.Ident("Peer")
+	header := httpPackage.Ident("Header")
+	conn := tripleProtocolPackage.Ident("StreamingHandlerConn")
+	for _, s := range t.Services {
+		for _, m := range s.Methods {
+			iface := s.GoName + "_" + m.GoName + "Server"
+			impl := s.GoName + m.GoName + "Server"
+			switch {
+			case m.StreamsRequest && m.StreamsReturn:
+				g.P("type ", iface, " interface {")
+				g.P("Send(*", m.ReturnType, ") error")
+				g.P("Recv() (*", m.RequestType, ", error)")
+				genAllDecl(g, m.RequestType)
+				g.P("Spec() ", spec)
+				g.P("Peer() ", peer)
+				g.P("RequestHeader() ", header)
+				g.P("ResponseHeader() ", header)
+				g.P("ResponseTrailer() ", header)
+				g.P("Conn() ", conn)
+				g.P("}")
+				g.P()
+				g.P("type ", impl, " struct {")
+				g.P("*", tripleProtocolPackage.Ident("BidiStream"))
+				g.P("}")
+				g.P()
+				g.P("func (srv *", impl, ") Send(msg *", m.ReturnType, ") error {")
+				g.P("return srv.BidiStream.Send(msg)")
+				g.P("}")
+				g.P()
+				g.P("func (srv ", impl, ") Recv() (*", m.RequestType, ", error) {")
+				g.P("msg := new(", m.RequestType, ")")
+				g.P("if err := srv.BidiStream.Receive(msg); err != nil {")
+				g.P("return nil, err")
+				g.P("}")
+				g.P("return msg, nil")
+				g.P("}")
+				g.P()
+				genAll(g, "srv", impl, m.RequestType, true)
+			case m.StreamsRequest:
+				g.P("type ", iface, " interface {")
+				g.P("Spec() ", spec)
+				g.P("Peer() ", peer)
+				g.P("Recv() bool")
+				g.P("RequestHeader() ", header)
+				g.P("Msg() *", m.RequestType)
+				g.P("Err() error")
+				genAllDecl(g, m.RequestType)
+				g.P("Conn() ", conn)
+				g.P("}")
+				g.P()
+				g.P("type ", impl, " struct {")
+				g.P("*", tripleProtocolPackage.Ident("ClientStream"))
+				g.P("}")
+				g.P()
+				g.P("func (srv *", impl, ") Recv() bool {")
+				g.P("msg := new(", m.RequestType, ")")
+				g.P("return srv.ClientStream.Receive(msg)")
+				g.P("}")
+				g.P()
+				g.P("func (srv *", impl, ") Msg() *", m.RequestType, " {")
+				g.P("msgRaw := srv.ClientStream.Msg()")
+				g.P("if msgRaw == nil {")
+				g.P("return new(", m.RequestType, ")")
+				g.P("}")
+				g.P("return msgRaw.(*", m.RequestType, ")")
+				g.P("}")
+				g.P()
+				genAll(g, "srv", impl, m.RequestType, false)
+			case m.StreamsReturn:
+				g.P("type ", iface, " interface {")
+				g.P("Send(*", m.ReturnType, ") error")
+				g.P("ResponseHeader() ", header)
+				g.P("ResponseTrailer() ", header)
+				g.P("Conn() ", conn)
+				g.P("}")
+				g.P()
+				g.P("type ", impl, " struct {")
+				g.P("*", tripleProtocolPackage.Ident("ServerStream"))
+				g.P("}")
+				g.P()
+				g.P("func (g *", impl, ") Send(msg *", m.ReturnType, ") error {")
+				g.P("return g.ServerStream.Send(msg)")
+				g.P("}")
+				g.P()
+			}
+		}
+	}
+}
+
+func genServiceInfo(g *protogen.GeneratedFile, t TripleGo) {
+	for _, s := range t.Services {
+		g.P("var ", s.GoName, "_ServiceInfo = ", serverPackage.Ident("ServiceInfo"), "{")
+		g.P("InterfaceName: ", interfaceNameExpr(s), ",")
+		g.P("ServiceType: (*", s.GoName, "Handler)(nil),")
+		g.P("Methods: []", serverPackage.Ident("MethodInfo"), "{")
+		for _, m := range s.Methods {
+			genMethodInfoEntry(g, s, m)
+		}
+		g.P("},")
+		g.P("Meta: map[string]interface{}{")
+		g.P(strconv.Quote(serviceDescriptorMeta), ": ", s.GoName, "Descriptor,")
+		genOptionsMeta(g, s.Options)
+		g.P("},")
+		g.P("}")
+		g.P()
+	}
+}
+
+func genMethodInfoEntry(g *protogen.GeneratedFile, s Service, m Method) {
+	stream := s.GoName + "_" + m.GoName + "Server"
+	impl := s.GoName + m.GoName + "Server"
+	handler := "handler.(" + s.GoName + "Handler)." + m.GoName
+	g.P("{")
+	g.P("Name: ", strconv.Quote(m.MethodName), ",")
+	switch {
+	case m.StreamsRequest && m.StreamsReturn:
+		g.P("Type: ", constantPackage.Ident("CallBidiStream"), ",")
+		g.P("StreamInitFunc: func(baseStream interface{}) interface{} {")
+		g.P("return &", impl, "{baseStream.(*", tripleProtocolPackage.Ident("BidiStream"), ")}")
+		g.P("},")
+		g.P("MethodFunc: func(ctx ", contextPackage.Ident("Context"), ", args []interface{}, handler interface{}) (interface{}, error) {")
+		g.P("stream := args[0].(", stream, ")")
+		g.P("if err := ", handler, "(ctx, stream); err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return nil, nil")
+		g.P("},")
+	case m.StreamsRequest:
+		g.P("Type: ", constantPackage.Ident("CallClientStream"), ",")
+		g.P("StreamInitFunc: func(baseStream interface{}) interface{} {")
+		g.P("return &", impl, "{baseStream.(*", tripleProtocolPackage.Ident("ClientStream"), ")}")
+		g.P("},")
+		g.P("MethodFunc: func(ctx ", contextPackage.Ident("Context"), ", args []interface{}, handler interface{}) (interface{}, error) {")
+		g.P("stream := args[0].(", stream, ")")
+		g.P("res, err := ", handler, "(ctx, stream)")
+		g.P("if err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return ", tripleProtocolPackage.Ident("NewResponse"), "(res), nil")
+		g.P("},")
+	case m.StreamsReturn:
+		g.P("Type: ", constantPackage.Ident("CallServerStream"), ",")
+		g.P("ReqInitFunc: func() interface{} {")
+		g.P("return new(", m.RequestType, ")")
+		g.P("},")
+		g.P("StreamInitFunc: func(baseStream interface{}) interface{} {")
+		g.P("return &", impl, "{baseStream.(*", tripleProtocolPackage.Ident("ServerStream"), ")}")
+		g.P("},")
+		g.P("MethodFunc: func(ctx ", contextPackage.Ident("Context"), ", args []interface{}, handler interface{}) (interface{}, error) {")
+		g.P("req := args[0].(*", m.RequestType, ")")
+		if validateHandler() && m.validates() {
+			genHandlerValidateCall(g, s, "handler.("+s.GoName+"Handler)", "req")
+		}
+		g.P("stream := args[1].(", stream, ")")
+		g.P("if err := ", handler, "(ctx, req, stream); err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return nil, nil")
+		g.P("},")
+	default:
+		g.P("Type: ", constantPackage.Ident("CallUnary"), ",")
+		g.P("ReqInitFunc: func() interface{} {")
+		g.P("return new(", m.RequestType, ")")
+		g.P("},")
+		g.P("MethodFunc: func(ctx ", contextPackage.Ident("Context"), ", args []interface{}, handler interface{}) (interface{}, error) {")
+		g.P("req := args[0].(*", m.RequestType, ")")
+		if validateHandler() && m.validates() {
+			genHandlerValidateCall(g, s, "handler.("+s.GoName+"Handler)", "req")
+		}
+		g.P("res, err := ", handler, "(ctx, req)")
+		g.P("if err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return ", tripleProtocolPackage.Ident("NewResponse"), "(res), nil")
+		g.P("},")
+	}
+	g.P("Meta: map[string]interface{}{")
+	g.P(strconv.Quote(methodDescriptorMeta), ": func() ", protoreflectPackage.Ident("MethodDescriptor"), " {")
+	g.P("return ", s.GoName, "Descriptor().Methods().ByName(", strconv.Quote(m.MethodName), ")")
+	g.P("},")
+	genOptionsMeta(g, m.Options)
+	genIdempotencyMeta(g, m)
+	genErrorsMeta(g, m)
+	g.P("},")
+	g.P("},")
+}
+
+// protoComments joins the leading and trailing comments of a proto element into
+// a single Go comment block. It returns an empty string when there are none.
+func protoComments(comments protogen.CommentSet) string {
+	var blocks []string
+	for _, c := range []protogen.Comments{comments.Leading, comments.Trailing} {
+		if c != "" {
+			blocks = append(blocks, strings.TrimSuffix(c.String(), "\n"))
+		}
+	}
+	return strings.Join(blocks, "\n//\n")
+}
+
+// genServiceComments appends the proto comments and the deprecation notice of s
+// to a doc comment that has already been started.
+func genServiceComments(g *protogen.GeneratedFile, s Service) {
+	if doc := protoComments(s.Comments); doc != "" {
+		g.P("//")
+		g.P(doc)
+	}
+	if s.Deprecated {
+		g.P("//")
+		g.P(deprecationComment)
+	}
+}
+
+// genMethodComments writes the proto comments and the deprecation notice of m as
+// its doc comment.
+func genMethodComments(g *protogen.GeneratedFile, m Method) {
+	doc := protoComments(m.Comments)
+	if doc != "" {
+		g.P(doc)
+	}
+	if m.Deprecated {
+		if doc != "" {
+			g.P("//")
+		}
+		g.P(deprecationComment)
+	}
+}
+
+// deprecationWarningIdent is the sync.Once guarding the deprecation warning of m.
+func deprecationWarningIdent(s Service, m Method) string {
+	return unexported(s.GoName + m.GoName + "DeprecationWarning")
+}
+
+// unexported returns name with a lower-case first letter. Names that would then
+// clash with a Go keyword or predeclared identifier get a trailing underscore.
+func unexported(name string) string {
+	name = util.ToLower(name)
+	if token.IsKeyword(name) || types.Universe.Lookup(name) != nil {
+		name += "_"
+	}
+	return name
+}
+
+// isStream reports whether either side of m streams.
+func (m Method) isStream() bool {
+	return m.StreamsRequest || m.StreamsReturn
+}
+
+// hasStream reports whether any method of s streams.
+func hasStream(s Service) bool {
+	for _, m := range s.Methods {
+		if m.isStream() {
+			return true
+		}
+	}
+	return false
+}
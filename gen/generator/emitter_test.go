@@ -0,0 +1,340 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+import (
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestGenClientServer(t *testing.T) {
+	// Both sides include their type checks, aligned by gofmt.
+	clientSide := []string{
+		"type GreetService interface {",
+		"func NewGreetService(cli *client.Client, opts ...client.ReferenceOption) (GreetService, error) {",
+		"type GreetServiceImpl struct {",
+		"var GreetService_ClientInfo = client.ClientInfo{",
+		"= (*GreetServiceImpl)(nil)",
+		"= (*GreetServiceGreetBidiClient)(nil)",
+		`"dubbo.apache.org/dubbo-go/v3/client"`,
+	}
+	serverSide := []string{
+		"type GreetServiceHandler interface {",
+		"func RegisterGreetServiceHandler(srv *server.Server, hdlr GreetServiceHandler, opts ...server.ServiceOption) error {",
+		"type UnimplementedGreetServiceHandler struct{}",
+		"var GreetService_ServiceInfo = server.ServiceInfo{",
+		"= (*UnimplementedGreetServiceHandler)(nil)",
+		"= (*GreetServiceGreetBidiServer)(nil)",
+		`"dubbo.apache.org/dubbo-go/v3/server"`,
+	}
+	// The in-process client is opt-in.
+	inProcess := []string{
+		"func NewGreetServiceInProcess(handler GreetServiceHandler) *GreetServiceInProcess {",
+		"type GreetServiceInProcess struct {",
+	}
+	tests := []struct {
+		params                    []string
+		client, server, inProcess bool
+	}{
+		{client: true, server: true},
+		{params: []string{"inprocess=true"}, client: true, server: true, inProcess: true},
+		{params: []string{"client=false"}, server: true},
+		{params: []string{"server=false"}, client: true},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(append([]string{"default"}, tt.params...), ","), func(t *testing.T) {
+			setParams(t, tt.params...)
+			if err := CheckOptions(); err != nil {
+				t.Fatal(err)
+			}
+			plugin := newPlugin(t, newRequest(t, `
+				name: "greet.proto"
+				package: "greet"
+				options { go_package: "example.com/greet" }
+				`+greetMessages+greetStreams))
+			stubs := generate(t, plugin, "greet.proto")
+			for _, group := range []struct {
+				wants []string
+				want  bool
+			}{
+				{clientSide, tt.client},
+				{serverSide, tt.server},
+				{inProcess, tt.inProcess},
+			} {
+				for _, want := range group.wants {
+					if strings.Contains(stubs, want) != group.want {
+						t.Errorf("stubs contain %q: %v, want %v", want, !group.want, group.want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestInProcessNeedsClientAndServer(t *testing.T) {
+	for _, params := range [][]string{{"inprocess=true", "client=false"}, {"inprocess=true", "server=false"}} {
+		setParams(t, params...)
+		if err := CheckOptions(); err == nil {
+			t.Errorf("CheckOptions accepted %s", strings.Join(params, ","))
+		}
+	}
+}
+
+func TestGenComments(t *testing.T) {
+	setParams(t)
+	req := newRequest(t, `
+		name: "greet.proto"
+		package: "greet"
+		options { go_package: "example.com/greet" }
+		`+greetMessages+`
+		service { name: "GreetService" `+rpc("Greet")+` }
+		source_code_info {
+			location { path: [6, 0] span: [3, 0, 5, 1] leading_comments: " Greets callers.\n" }
+			location { path: [6, 0, 2, 0] span: [4, 2, 40] leading_comments: " Returns a greeting.\n" trailing_comments: " Never fails.\n" }
+		}`)
+	req.Parameter = proto.String("annotate_code")
+	plugin := newPlugin(t, req)
+	stubs := generate(t, plugin, "greet.proto")
+	for _, want := range []string{
+		"// GreetService is a client for the greet.GreetService service.\n//\n// Greets callers.\ntype GreetService interface {",
+		"// NewGreetService constructs a client for the greet.GreetService service.\n//\n// Greets callers.\nfunc NewGreetService(",
+		"// GreetServiceHandler is an implementation of the greet.GreetService service.\n//\n// Greets callers.\ntype GreetServiceHandler interface {",
+		"// Returns a greeting.\n\t//\n\t// Never fails.\n\tGreet(ctx context.Context, req *GreetRequest, opts ...client.CallOption) (*GreetResponse, error)",
+		"// Returns a greeting.\n\t//\n\t// Never fails.\n\tGreet(context.Context, *GreetRequest) (*GreetResponse, error)",
+	} {
+		if !strings.Contains(stubs, want) {
+			t.Errorf("stubs lack %q", want)
+		}
+	}
+
+	// The annotations lead from the interfaces and their methods back to the
+	// service and the method in the .proto file.
+	var meta string
+	for _, file := range plugin.Response().File {
+		if strings.HasSuffix(file.GetName(), ".triple.go.meta") {
+			meta = file.GetContent()
+		}
+	}
+	info := &descriptorpb.GeneratedCodeInfo{}
+	if err := prototext.Unmarshal([]byte(meta), info); err != nil {
+		t.Fatalf("parsing annotations: %v", err)
+	}
+	annotated := map[string]bool{}
+	for _, a := range info.Annotation {
+		annotated[stubs[a.GetBegin():a.GetEnd()]+" "+fmt.Sprint(a.GetPath())] = true
+	}
+	for _, want := range []string{
+		"GreetService [6 0]",
+		"NewGreetService [6 0]",
+		"GreetServiceHandler [6 0]",
+		"RegisterGreetServiceHandler [6 0]",
+		"Greet [6 0 2 0]",
+	} {
+		if !annotated[want] {
+			t.Errorf("no annotation %s in %v", want, annotated)
+		}
+	}
+}
+
+func TestGenDeprecated(t *testing.T) {
+	file := func(options string) string {
+		return `
+		name: "greet.proto"
+		package: "greet"
+		options { go_package: "example.com/greet" ` + options + ` }
+		` + greetMessages + `
+		service {
+			name: "GreetService"
+			` + rpc("Greet") + `
+			method { name: "Old" input_type: ".greet.GreetRequest" output_type: ".greet.GreetResponse" options { deprecated: true } }
+		}
+		service { name: "OldService" options { deprecated: true } ` + rpc("Greet") + ` }`
+	}
+	deprecated := []string{
+		"// Deprecated: Do not use.\n\tGreetServiceOldProcedure = ",
+		"// Deprecated: Do not use.\n\tOldServiceName = ",
+		"// Deprecated: Do not use.\n\tOldServiceGreetProcedure = ",
+		"// Deprecated: Do not use.\n\tOld(ctx context.Context, req *GreetRequest, opts ...client.CallOption) (*GreetResponse, error)",
+		"// Deprecated: Do not use.\nfunc (c *GreetServiceImpl) Old(",
+		"// Deprecated: Do not use.\n\tOld(context.Context, *GreetRequest) (*GreetResponse, error)",
+		"// Deprecated: Do not use.\ntype OldService interface {",
+		"// Deprecated: Do not use.\nfunc NewOldService(",
+		"// Deprecated: Do not use.\nfunc SetConsumerOldService(",
+		"// Deprecated: Do not use.\ntype OldServiceHandler interface {",
+		"// Deprecated: Do not use.\nfunc RegisterOldServiceHandler(",
+		"// Deprecated: Do not use.\nfunc SetProviderOldService(",
+	}
+	current := []string{
+		"// Deprecated: Do not use.\n\tGreetServiceGreetProcedure = ",
+		"// Deprecated: Do not use.\ntype GreetService interface {",
+		"// Deprecated: Do not use.\nfunc NewGreetService(",
+		"// Deprecated: Do not use.\nfunc (c *GreetServiceImpl) Greet(",
+	}
+	warnings := []string{
+		"var greetServiceOldDeprecationWarning sync.Once",
+		"greetServiceOldDeprecationWarning.Do(func() {\n\t\tlogger.Warnf(\"%s is deprecated\", GreetServiceOldProcedure)",
+		"oldServiceGreetDeprecationWarning.Do(",
+	}
+	tests := []struct {
+		name    string
+		params  []string
+		options string
+		// deprecated is set when the file is deprecated, and with it everything.
+		deprecated, warn bool
+	}{
+		{name: "default"},
+		{name: "warn", params: []string{"warn_deprecated=true"}, warn: true},
+		{name: "deprecated file", options: "deprecated: true", deprecated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setParams(t, tt.params...)
+			stubs := generate(t, newPlugin(t, newRequest(t, file(tt.options))), "greet.proto")
+			for _, group := range []struct {
+				wants []string
+				want  bool
+			}{
+				{deprecated, true},
+				{current, tt.deprecated},
+				{warnings, tt.warn},
+			} {
+				for _, want := range group.wants {
+					if strings.Contains(stubs, want) != group.want {
+						t.Errorf("stubs contain %q: %v, want %v", want, !group.want, group.want)
+					}
+				}
+			}
+			if got := strings.Contains(stubs, "// greet.proto is a deprecated file."); got != tt.deprecated {
+				t.Errorf("stubs mark greet.proto deprecated: %v, want %v", got, tt.deprecated)
+			}
+		})
+	}
+}
+
+func TestGenUnimplementedHandler(t *testing.T) {
+	unimplemented := []string{
+		"func (UnimplementedGreetServiceHandler) Greet(context.Context, *GreetRequest) (*GreetResponse, error) {\n\treturn nil, triple_protocol.NewError(triple_protocol.CodeUnimplemented, errors.New(\"greet.GreetService.Greet is not implemented\"))",
+		"func (UnimplementedGreetServiceHandler) GreetClient(context.Context, GreetService_GreetClientServer) (*GreetResponse, error) {\n\treturn nil, triple_protocol.NewError(triple_protocol.CodeUnimplemented, errors.New(\"greet.GreetService.GreetClient is not implemented\"))",
+		"func (UnimplementedGreetServiceHandler) GreetServer(context.Context, *GreetRequest, GreetService_GreetServerServer) error {\n\treturn triple_protocol.NewError(triple_protocol.CodeUnimplemented, errors.New(\"greet.GreetService.GreetServer is not implemented\"))",
+		"func (UnimplementedGreetServiceHandler) GreetBidi(context.Context, GreetService_GreetBidiServer) error {\n\treturn triple_protocol.NewError(triple_protocol.CodeUnimplemented, errors.New(\"greet.GreetService.GreetBidi is not implemented\"))",
+	}
+	required := []string{
+		"\tmustEmbedUnimplementedGreetServiceHandler()\n}",
+		"func (UnimplementedGreetServiceHandler) mustEmbedUnimplementedGreetServiceHandler() {}",
+	}
+	for _, require := range []bool{false, true} {
+		setParams(t, fmt.Sprintf("require_unimplemented_handlers=%v", require))
+		stubs := generate(t, newPlugin(t, newRequest(t, `
+			name: "greet.proto"
+			package: "greet"
+			options { go_package: "example.com/greet" }
+			`+greetMessages+greetStreams)), "greet.proto")
+		for _, want := range unimplemented {
+			if !strings.Contains(stubs, want) {
+				t.Errorf("stubs lack %q", want)
+			}
+		}
+		for _, want := range required {
+			if strings.Contains(stubs, want) != require {
+				t.Errorf("with require_unimplemented_handlers=%v, stubs contain %q: %v", require, want, !require)
+			}
+		}
+	}
+}
+
+func TestGenGoNames(t *testing.T) {
+	setParams(t)
+	stubs := generate(t, newPlugin(t, newRequest(t, `
+		name: "greet.proto"
+		package: "greet"
+		options { go_package: "example.com/greet" }
+		`+greetMessages+`
+		service {
+			name: "greet_service"
+			`+rpc("say_hello")+`
+			method { name: "watch_hello" input_type: ".greet.GreetRequest" output_type: ".greet.GreetResponse" server_streaming: true }
+		}`)), "greet.proto")
+	// Go identifiers are camel-cased, the names on the wire are the declared ones.
+	for _, want := range []string{
+		`GreetServiceName = "greet.greet_service"`,
+		`GreetServiceSayHelloProcedure = "/greet.greet_service/say_hello"`,
+		"type GreetService interface {",
+		"SayHello(ctx context.Context, req *GreetRequest, opts ...client.CallOption) (*GreetResponse, error)",
+		"WatchHello(ctx context.Context, req *GreetRequest, opts ...client.CallOption) (GreetService_WatchHelloClient, error)",
+		`c.conn.CallUnary(ctx, []interface{}{req}, resp, "say_hello", opts...)`,
+		`c.conn.CallServerStream(ctx, req, "watch_hello", opts...)`,
+		"type GreetServiceWatchHelloClient struct {",
+		"type GreetServiceHandler interface {",
+		"WatchHello(context.Context, *GreetRequest, GreetService_WatchHelloServer) error",
+		`MethodNames:   []string{"say_hello", "watch_hello"},`,
+		`Name: "say_hello",`,
+	} {
+		if !strings.Contains(stubs, want) {
+			t.Errorf("stubs lack %q", want)
+		}
+	}
+	for _, unwanted := range []string{"Say_hello", "greet_serviceHandler", "GreetServicesay_hello"} {
+		if strings.Contains(stubs, unwanted) {
+			t.Errorf("stubs contain %q", unwanted)
+		}
+	}
+}
+
+func TestUnexported(t *testing.T) {
+	for name, want := range map[string]string{
+		"GreetServiceGreetDeprecationWarning": "greetServiceGreetDeprecationWarning",
+		"Func":                                "func_",
+		"Len":                                 "len_",
+		"Error":                               "error_",
+		"String":                              "string_",
+	} {
+		if got := unexported(name); got != want {
+			t.Errorf("unexported(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestGenDescriptors(t *testing.T) {
+	for _, tt := range []struct {
+		params     []string
+		descriptor string
+	}{
+		{descriptor: "File_greet_v1_greet_proto"},
+		// The stubs in a sub-package refer to the descriptor of the .pb.go files.
+		{params: []string{"package_suffix=triple"}, descriptor: "v1.File_greet_v1_greet_proto"},
+	} {
+		setParams(t, tt.params...)
+		stubs := generate(t, newPlugin(t, newRequest(t, greetV1Proto)), "greet/v1/greet.proto")
+		for _, want := range []string{
+			"func GreetServiceDescriptor() protoreflect.ServiceDescriptor {\n\treturn " + tt.descriptor + `.Services().ByName("GreetService")`,
+			`"ServiceDescriptor": GreetServiceDescriptor,`,
+			"\"MethodDescriptor\": func() protoreflect.MethodDescriptor {\n\t\t\t\t\treturn GreetServiceDescriptor().Methods().ByName(\"Greet\")",
+		} {
+			if !strings.Contains(stubs, want) {
+				t.Errorf("with %v, stubs lack %q", tt.params, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,241 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/dubbogo/protoc-gen-go-triple/v3/dubbo"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// errorsMeta is the Meta key of the errors a method declares: a map from the
+// full name of their detail message to their triple_protocol.Code.
+const errorsMeta = "errors"
+
+// tripleCodes maps the names of error codes, as used in the dubbo.method option
+// and on the wire, to their triple_protocol constants.
+var tripleCodes = map[string]string{
+	"canceled":            "CodeCanceled",
+	"unknown":             "CodeUnknown",
+	"invalid_argument":    "CodeInvalidArgument",
+	"deadline_exceeded":   "CodeDeadlineExceeded",
+	"not_found":           "CodeNotFound",
+	"already_exists":      "CodeAlreadyExists",
+	"permission_denied":   "CodePermissionDenied",
+	"resource_exhausted":  "CodeResourceExhausted",
+	"failed_precondition": "CodeFailedPrecondition",
+	"aborted":             "CodeAborted",
+	"out_of_range":        "CodeOutOfRange",
+	"unimplemented":       "CodeUnimplemented",
+	"internal":            "CodeInternal",
+	"unavailable":         "CodeUnavailable",
+	"data_loss":           "CodeDataLoss",
+	"unauthenticated":     "CodeUnauthenticated",
+}
+
+// MethodError is an error a method declares with the errors of its dubbo.method
+// option.
+type MethodError struct {
+	// Detail is the message carried as detail of the error, and Code the name of
+	// the triple_protocol constant of its code.
+	Detail *protogen.Message
+	Code   string
+	// Constructor is the name of the New{Method}{Detail}Error function handlers
+	// return the error with.
+	Constructor string
+}
+
+// methodErrors resolves the errors method declares. Their detail messages are
+// looked up in the files of the request, which include all imports.
+func methodErrors(plugin *protogen.Plugin, method *protogen.Method) ([]MethodError, error) {
+	var errs []MethodError
+	seen := make(map[protoreflect.FullName]bool)
+	for _, decl := range declaredErrors(method) {
+		name := protoreflect.FullName(strings.TrimPrefix(decl.GetDetail(), "."))
+		detail := findMessage(plugin, name)
+		if detail == nil {
+			return nil, fmt.Errorf("dubbo option of %s: error detail %s is not a message of the file or its imports", method.Desc.FullName(), name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("dubbo option of %s: error detail %s is declared twice", method.Desc.FullName(), name)
+		}
+		seen[name] = true
+		code := decl.GetCode()
+		if code == "" {
+			code = "unknown"
+		}
+		ident, ok := tripleCodes[code]
+		if !ok {
+			return nil, fmt.Errorf("dubbo option of %s: unknown error code %q", method.Desc.FullName(), code)
+		}
+		errs = append(errs, MethodError{Detail: detail, Code: ident})
+	}
+	return errs, nil
+}
+
+func declaredErrors(method *protogen.Method) []*dubbo.MethodError {
+	ext, _ := proto.GetExtension(method.Desc.Options(), dubbo.E_Method).(*dubbo.MethodOptions)
+	return ext.GetErrors()
+}
+
+func findMessage(plugin *protogen.Plugin, name protoreflect.FullName) *protogen.Message {
+	var find func(messages []*protogen.Message) *protogen.Message
+	find = func(messages []*protogen.Message) *protogen.Message {
+		for _, m := range messages {
+			if m.Desc.FullName() == name {
+				return m
+			}
+			if found := find(m.Messages); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	for _, f := range plugin.Files {
+		if found := find(f.Messages); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// ErrorDetail is a detail message of declared errors, with the name of the
+// As{Detail} function extracting it.
+type ErrorDetail struct {
+	Message   *protogen.Message
+	Extractor string
+}
+
+// nameErrors names the New{Method}{Detail}Error constructors and the As{Detail}
+// extractors of the errors declared by services, the services of one file. The
+// extractor of a detail is declared by the first service returning it. Names
+// that other files or details of the Go package generate too are reported by
+// CheckCollisions.
+func nameErrors(services []Service) {
+	extracted := make(map[protoreflect.FullName]bool)
+	for i := range services {
+		s := &services[i]
+		for j := range s.Methods {
+			m := &s.Methods[j]
+			for k := range m.Errors {
+				e := &m.Errors[k]
+				e.Constructor = "New" + m.GoName + e.Detail.GoIdent.GoName + "Error"
+				if name := e.Detail.Desc.FullName(); !extracted[name] {
+					extracted[name] = true
+					s.ErrorDetails = append(s.ErrorDetails, ErrorDetail{
+						Message:   e.Detail,
+						Extractor: "As" + e.Detail.GoIdent.GoName,
+					})
+				}
+			}
+		}
+	}
+}
+
+// genErrorConstructors writes the New{Method}{Detail}Error constructors handlers
+// return declared errors with.
+func genErrorConstructors(g *protogen.GeneratedFile, t TripleGo) {
+	for _, s := range t.Services {
+		for _, m := range s.Methods {
+			for _, e := range m.Errors {
+				name := e.Constructor
+				g.P("// ", name, " returns an error of the ", m.MethodName, " method with the given")
+				g.P("// message, carrying detail. Its code is ", e.Code, ".")
+				g.P("func ", name, "(message string, detail *", e.Detail.GoIdent, ") *", tripleProtocolPackage.Ident("Error"), " {")
+				g.P("err := ", tripleProtocolPackage.Ident("NewError"), "(", tripleProtocolPackage.Ident(e.Code), ", ", errorsPackage.Ident("New"), "(message))")
+				g.P("if errDetail, detailErr := ", tripleProtocolPackage.Ident("NewErrorDetail"), "(detail); detailErr == nil {")
+				g.P("err.AddDetail(errDetail)")
+				g.P("}")
+				g.P("return err")
+				g.P("}")
+				g.P()
+			}
+		}
+	}
+}
+
+// genErrorExtractors writes the As{Detail} functions clients decode declared
+// errors with.
+func genErrorExtractors(g *protogen.GeneratedFile, t TripleGo) {
+	for _, s := range t.Services {
+		for _, d := range s.ErrorDetails {
+			name, detail := d.Extractor, d.Message
+			g.P("// ", name, " returns the ", detail.Desc.FullName(), " detail of err, and whether err is a")
+			g.P("// triple error carrying one.")
+			g.P("func ", name, "(err error) (*", detail.GoIdent, ", bool) {")
+			g.P("var tripleErr *", tripleProtocolPackage.Ident("Error"))
+			g.P("if !", errorsPackage.Ident("As"), "(err, &tripleErr) {")
+			g.P("return nil, false")
+			g.P("}")
+			g.P("for _, errDetail := range tripleErr.Details() {")
+			g.P("value, valueErr := errDetail.Value()")
+			g.P("if valueErr != nil {")
+			g.P("continue")
+			g.P("}")
+			g.P("if detail, ok := value.(*", detail.GoIdent, "); ok {")
+			g.P("return detail, true")
+			g.P("}")
+			g.P("}")
+			g.P("return nil, false")
+			g.P("}")
+			g.P()
+		}
+	}
+}
+
+// genErrorsMeta writes the errors m declares into its MethodInfo Meta.
+func genErrorsMeta(g *protogen.GeneratedFile, m Method) {
+	if len(m.Errors) == 0 {
+		return
+	}
+	g.P(strconv.Quote(errorsMeta), ": map[string]", tripleProtocolPackage.Ident("Code"), "{")
+	for _, e := range m.Errors {
+		g.P(strconv.Quote(string(e.Detail.Desc.FullName())), ": ", tripleProtocolPackage.Ident(e.Code), ",")
+	}
+	g.P("},")
+}
+
+// errorIdents lists the package-level identifiers s declares for its errors:
+// the constructors when handlers are generated and the extractors when clients
+// are.
+func errorIdents(s Service) []string {
+	var idents []string
+	if *Server {
+		for _, m := range s.Methods {
+			for _, e := range m.Errors {
+				idents = append(idents, e.Constructor)
+			}
+		}
+	}
+	if *Client {
+		for _, detail := range s.ErrorDetails {
+			idents = append(idents, detail.Extractor)
+		}
+	}
+	return idents
+}
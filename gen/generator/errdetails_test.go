@@ -0,0 +1,125 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+// erring declares a unary method returning errors with the given details.
+func erring(name string, details ...string) string {
+	var errs []string
+	for _, detail := range details {
+		errs = append(errs, `errors { detail: "`+detail+`" code: "not_found" }`)
+	}
+	return `method {
+		name: "` + name + `" input_type: ".greet.GreetRequest" output_type: ".greet.GreetResponse"
+		options { [dubbo.method] { ` + strings.Join(errs, " ") + ` } }
+	}`
+}
+
+func TestNameErrors(t *testing.T) {
+	setParams(t)
+	plugin := newPlugin(t, newRequest(t, `
+		name: "greet.proto"
+		package: "greet"
+		options { go_package: "example.com/greet" }
+		`+greetMessages+`
+		message_type { name: "NotFound" }
+		message_type { name: "Gone" }
+		service { name: "GreetService" `+erring("Get", "greet.NotFound")+erring("Delete", "greet.NotFound", "greet.Gone")+` }
+		service { name: "AdminService" `+erring("Find", "greet.NotFound")+` }`))
+	if err := CheckCollisions(plugin); err != nil {
+		t.Fatalf("CheckCollisions: %v", err)
+	}
+	stubs := generate(t, plugin, "greet.proto")
+	for _, want := range []string{
+		"func NewGetNotFoundError(message string, detail *NotFound) *triple_protocol.Error {",
+		"func NewDeleteNotFoundError(message string, detail *NotFound) *triple_protocol.Error {",
+		"func NewDeleteGoneError(message string, detail *Gone) *triple_protocol.Error {",
+		"func NewFindNotFoundError(message string, detail *NotFound) *triple_protocol.Error {",
+		"func AsNotFound(err error) (*NotFound, bool) {",
+		"func AsGone(err error) (*Gone, bool) {",
+	} {
+		if !strings.Contains(stubs, want) {
+			t.Errorf("stubs lack %q", want)
+		}
+	}
+	// Services of a file share the extractors of their details.
+	if n := strings.Count(stubs, "func AsNotFound("); n != 1 {
+		t.Errorf("stubs declare AsNotFound %d times", n)
+	}
+}
+
+func TestNameErrorsCollisions(t *testing.T) {
+	setParams(t)
+	other := `
+		name: "other.proto"
+		package: "other"
+		options { go_package: "example.com/other" }
+		message_type { name: "NotFound" }`
+	greet := func(services string) string {
+		return `
+		name: "greet.proto"
+		package: "greet"
+		dependency: "other.proto"
+		options { go_package: "example.com/greet" }
+		` + greetMessages + `
+		message_type { name: "NotFound" }
+		` + services
+	}
+	tests := []struct {
+		name  string
+		files []string
+		want  string
+	}{
+		{
+			name: "method of two services",
+			files: []string{greet(`
+				service { name: "GreetService" ` + erring("Get", "greet.NotFound") + ` }
+				service { name: "AdminService" ` + erring("Get", "greet.NotFound") + ` }`)},
+			want: `identifier "NewGetNotFoundError" is generated for both service greet.GreetService (greet.proto) and service greet.AdminService (greet.proto)`,
+		},
+		{
+			name: "details of the same Go name",
+			files: []string{greet(`
+				service { name: "GreetService" ` + erring("Get", "greet.NotFound", "other.NotFound") + ` }`)},
+			want: `identifier "AsNotFound" is generated for both service greet.GreetService (greet.proto) and service greet.GreetService (greet.proto)`,
+		},
+		{
+			name: "detail returned by two files of the package",
+			files: []string{greet(`
+				service { name: "GreetService" ` + erring("Get", "greet.NotFound") + ` }`), `
+				name: "library.proto"
+				package: "greet"
+				dependency: "greet.proto"
+				options { go_package: "example.com/greet" }
+				service { name: "LibraryService" ` + erring("Find", "greet.NotFound") + ` }`},
+			want: `identifier "AsNotFound" is generated for both service greet.GreetService (greet.proto) and service greet.LibraryService (library.proto)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckCollisions(newPlugin(t, newRequest(t, append([]string{other}, tt.files...)...)))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("CheckCollisions() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Patterns is a list of glob patterns selecting services and methods. A pattern
+// either matches fully-qualified service names, like "greet.v1.*", or methods
+// in the form "service/method", like "greet.v1.GreetService/Get*". The syntax
+// is that of path.Match, so "*" never crosses the "/".
+//
+// Patterns implements flag.Value; every occurrence of the flag adds a pattern.
+type Patterns []string
+
+func (p *Patterns) String() string {
+	if p == nil {
+		return ""
+	}
+	return strings.Join(*p, ",")
+}
+
+// Set adds pattern, failing if it is malformed or has more than one "/".
+func (p *Patterns) Set(pattern string) error {
+	if strings.Count(pattern, "/") > 1 {
+		return fmt.Errorf("invalid pattern %q: more than one /", pattern)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	*p = append(*p, pattern)
+	return nil
+}
+
+// Include and Exclude select the services and methods stubs are generated for.
+// With no Include patterns everything is included, and Exclude wins over Include.
+var (
+	Include Patterns
+	Exclude Patterns
+)
+
+// matchService reports whether the service part of pattern matches service.
+func matchService(pattern, service string) bool {
+	servicePattern, _, _ := strings.Cut(pattern, "/")
+	ok, _ := path.Match(servicePattern, service)
+	return ok
+}
+
+// matchMethod reports whether pattern matches method of service. A pattern
+// without a method part matches every method of the services it matches.
+func matchMethod(pattern, service, method string) bool {
+	_, methodPattern, hasMethod := strings.Cut(pattern, "/")
+	if !matchService(pattern, service) {
+		return false
+	}
+	if !hasMethod {
+		return true
+	}
+	ok, _ := path.Match(methodPattern, method)
+	return ok
+}
+
+// serviceSelected reports whether stubs may be generated for service at all. A
+// service is only excluded as a whole by patterns without a method part.
+func serviceSelected(service string) bool {
+	included := len(Include) == 0
+	for _, pattern := range Include {
+		included = included || matchService(pattern, service)
+	}
+	for _, pattern := range Exclude {
+		if !strings.Contains(pattern, "/") && matchService(pattern, service) {
+			return false
+		}
+	}
+	return included
+}
+
+// methodSelected reports whether stubs are generated for method of service.
+func methodSelected(service, method string) bool {
+	included := len(Include) == 0
+	for _, pattern := range Include {
+		included = included || matchMethod(pattern, service, method)
+	}
+	for _, pattern := range Exclude {
+		if matchMethod(pattern, service, method) {
+			return false
+		}
+	}
+	return included
+}
@@ -0,0 +1,173 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelected(t *testing.T) {
+	tests := []struct {
+		name   string
+		params []string
+		// services and methods map the fully-qualified names of services, and
+		// of methods as service/method, to whether they are selected.
+		services map[string]bool
+		methods  map[string]bool
+	}{
+		{
+			name:     "no patterns",
+			services: map[string]bool{"greet.v1.GreetService": true},
+			methods:  map[string]bool{"greet.v1.GreetService/Greet": true},
+		},
+		{
+			name:   "include services",
+			params: []string{"include=greet.v1.*"},
+			services: map[string]bool{
+				"greet.v1.GreetService": true,
+				"greet.v2.GreetService": false,
+				// "*" does not stop at dots.
+				"greet.v1.admin.AdminService": true,
+			},
+			methods: map[string]bool{
+				"greet.v1.GreetService/Greet": true,
+				"greet.v2.GreetService/Greet": false,
+			},
+		},
+		{
+			name:   "include methods",
+			params: []string{"include=greet.v1.GreetService/Get*", "include=*/Ping"},
+			services: map[string]bool{
+				"greet.v1.GreetService": true,
+				"other.OtherService":    true,
+			},
+			methods: map[string]bool{
+				"greet.v1.GreetService/GetGreeting": true,
+				"greet.v1.GreetService/Ping":        true,
+				"greet.v1.GreetService/Delete":      false,
+				"other.OtherService/Ping":           true,
+				"other.OtherService/GetOther":       false,
+			},
+		},
+		{
+			name:   "exclude wins over include",
+			params: []string{"include=greet.v1.*", "exclude=greet.v1.AdminService", "exclude=*/Internal*"},
+			services: map[string]bool{
+				"greet.v1.GreetService": true,
+				"greet.v1.AdminService": false,
+				"other.OtherService":    false,
+			},
+			methods: map[string]bool{
+				"greet.v1.GreetService/Greet":         true,
+				"greet.v1.GreetService/InternalReset": false,
+				"greet.v1.AdminService/Greet":         false,
+				"other.OtherService/Greet":            false,
+			},
+		},
+		{
+			// A method pattern excludes methods, not the service as a whole.
+			name:   "exclude methods",
+			params: []string{"exclude=greet.v1.GreetService/*"},
+			services: map[string]bool{
+				"greet.v1.GreetService": true,
+			},
+			methods: map[string]bool{
+				"greet.v1.GreetService/Greet": false,
+				"greet.v1.AdminService/Greet": true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setParams(t, tt.params...)
+			for service, want := range tt.services {
+				if got := serviceSelected(service); got != want {
+					t.Errorf("serviceSelected(%s) = %v, want %v", service, got, want)
+				}
+			}
+			for name, want := range tt.methods {
+				service, method, _ := strings.Cut(name, "/")
+				if got := methodSelected(service, method); got != want {
+					t.Errorf("methodSelected(%s, %s) = %v, want %v", service, method, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPatternsSet(t *testing.T) {
+	var p Patterns
+	for _, pattern := range []string{"[", "greet.*/[", `greet\`, "*/[a-", "a/b/c"} {
+		if err := p.Set(pattern); err == nil {
+			t.Errorf("Set(%q) accepted an invalid pattern", pattern)
+		}
+	}
+	if len(p) != 0 {
+		t.Errorf("invalid patterns were added: %v", p)
+	}
+	for _, pattern := range []string{"greet.v1.*", "*/Internal*", "greet.[a-z]*/Get?"} {
+		if err := p.Set(pattern); err != nil {
+			t.Errorf("Set(%q): %v", pattern, err)
+		}
+	}
+	if got := p.String(); got != "greet.v1.*,*/Internal*,greet.[a-z]*/Get?" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestFilteredServices(t *testing.T) {
+	setParams(t, "exclude=greet.GreetService/*", "exclude=*/Internal")
+	plugin := newPlugin(t, newRequest(t, `
+		name: "greet.proto"
+		package: "greet"
+		options { go_package: "example.com/greet" }
+		`+greetMessages+`
+		service { name: "GreetService" `+rpc("Greet")+rpc("Internal")+` }
+		service { name: "AdminService" `+rpc("Reset")+rpc("Internal")+` }`))
+	tripleGo, err := ProcessProtoFile(plugin, plugin.FilesByPath["greet.proto"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// GreetService has all its methods filtered out and is left out.
+	if len(tripleGo.Services) != 1 || tripleGo.Services[0].ServiceName != "AdminService" {
+		t.Fatalf("services = %+v, want only AdminService", tripleGo.Services)
+	}
+	if methods := tripleGo.Services[0].Methods; len(methods) != 1 || methods[0].MethodName != "Reset" {
+		t.Errorf("AdminService methods = %+v, want only Reset", methods)
+	}
+
+	stubs := generate(t, plugin, "greet.proto")
+	for _, unwanted := range []string{
+		"GreetService",
+		"Internal",
+	} {
+		if strings.Contains(stubs, unwanted) {
+			t.Errorf("generated stubs mention the filtered out %s", unwanted)
+		}
+	}
+	for _, want := range []string{
+		"type AdminServiceHandler interface {",
+		"var AdminService_ServiceInfo = server.ServiceInfo{",
+		"Reset(ctx context.Context, req *GreetRequest, opts ...client.CallOption) (*GreetResponse, error)",
+	} {
+		if !strings.Contains(stubs, want) {
+			t.Errorf("generated stubs lack %q", want)
+		}
+	}
+}
@@ -17,5 +17,98 @@
 
 package generator
 
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"strings"
+)
+
+// Plugin parameters understood by the v3 generator. main binds them to the
+// protoc parameter flags before any file is generated.
+var (
+	// WarnDeprecated makes generated clients log a one-time warning the first
+	// time a deprecated RPC is invoked.
+	WarnDeprecated = new(bool)
+	// RequireUnimplemented adds an unexported method to every handler interface
+	// so implementations have to embed the generated Unimplemented handler.
+	RequireUnimplemented = new(bool)
+	// PackageSuffix, when set, moves the stubs of every file into a sub-package
+	// of its message package, named after the message package plus the suffix.
+	PackageSuffix = new(string)
+	// Client and Server select the halves of the stubs that are generated: the
+	// client interface, its implementation and ClientInfo on one side, and the
+	// handler interface, its registration and ServiceInfo on the other.
+	Client = new(bool)
+	Server = new(bool)
+	// PerService writes the stubs of every service to a file of its own, named
+	// after the service, instead of one file per proto file.
+	PerService = new(bool)
+	// FileSuffix is appended to the name of every generated file.
+	FileSuffix = new(string)
+	// Mocks adds a _mock.go file next to every generated file, holding mocks of
+	// the client and handler interfaces and fakes of their streams.
+	Mocks = new(bool)
+	// OpenAPI is "true" for an OpenAPI document next to every generated file, or
+	// "merged" for a single document covering all of them.
+	OpenAPI = new(string)
+	// Validate is "true" to validate requests before they reach the handler, or
+	// "all" to validate them in the client as well.
+	Validate = new(string)
+	// InterfaceName is "java" to register services under the name of their Java
+	// interface instead of their fully-qualified proto name.
+	InterfaceName = new(string)
+	// Iterators is "true" to add an All method returning an iter.Seq2 to the
+	// stream interfaces that receive messages, or "func" to declare it with the
+	// function type underlying iter.Seq2 for Go versions before 1.23.
+	Iterators = new(string)
+	// InProcess adds New{Service}InProcess, a client calling a handler in the
+	// same process, for tests. It needs both the client and the server stubs.
+	InProcess = new(bool)
+	// IdempotentRetries is the number of times unary calls to IDEMPOTENT and
+	// NO_SIDE_EFFECTS methods are retried when the dubbo options set no retries.
+	IdempotentRetries = new(int)
+)
+
+// CheckOptions reports plugin parameters that cannot be used to generate code.
+func CheckOptions() error {
+	if *PackageSuffix != "" && !token.IsIdentifier("_"+*PackageSuffix) {
+		return fmt.Errorf("package_suffix %q is not a valid Go package name suffix", *PackageSuffix)
+	}
+	if !strings.HasSuffix(*FileSuffix, ".go") || *FileSuffix == ".pb.go" {
+		return fmt.Errorf("file_suffix %q must end in .go and differ from .pb.go", *FileSuffix)
+	}
+	switch *OpenAPI {
+	case "", "false", OpenAPIPerFile, OpenAPIMerged:
+	default:
+		return fmt.Errorf("openapi must be true, false or %s, not %q", OpenAPIMerged, *OpenAPI)
+	}
+	switch *Validate {
+	case "", "false", ValidateHandler, ValidateAll:
+	default:
+		return fmt.Errorf("validate must be true, false or %s, not %q", ValidateAll, *Validate)
+	}
+	switch *InterfaceName {
+	case "", InterfaceNameProto, InterfaceNameJava:
+	default:
+		return fmt.Errorf("interface_name must be %s or %s, not %q", InterfaceNameProto, InterfaceNameJava, *InterfaceName)
+	}
+	switch *Iterators {
+	case "", "false", IteratorsSeq, IteratorsFunc:
+	default:
+		return fmt.Errorf("iterators must be true, false or %s, not %q", IteratorsFunc, *Iterators)
+	}
+	if !*Client && !*Server {
+		return errors.New("client=false and server=false leave nothing to generate")
+	}
+	if *IdempotentRetries < 0 {
+		return fmt.Errorf("idempotent_retries %d must not be negative", *IdempotentRetries)
+	}
+	if *InProcess && !(*Client && *Server) {
+		return errors.New("inprocess=true needs both the client and the server stubs")
+	}
+	return nil
+}
+
 type Generator struct {
 }
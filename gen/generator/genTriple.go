@@ -18,8 +18,8 @@
 package generator
 
 import (
-	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -33,112 +33,6 @@ import (
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
-// buildPackageLookupMap creates a map for efficient package name to file lookup
-func buildPackageLookupMap(allFiles []*descriptorpb.FileDescriptorProto) map[string]*descriptorpb.FileDescriptorProto {
-	lookupMap := make(map[string]*descriptorpb.FileDescriptorProto)
-	for _, file := range allFiles {
-		lookupMap[file.GetPackage()] = file
-	}
-	return lookupMap
-}
-
-// buildDependencyLookupMap creates a map for efficient dependency path to file lookup
-func buildDependencyLookupMap(allFiles []*descriptorpb.FileDescriptorProto) map[string]*descriptorpb.FileDescriptorProto {
-	lookupMap := make(map[string]*descriptorpb.FileDescriptorProto)
-	for _, file := range allFiles {
-		lookupMap[file.GetName()] = file
-	}
-	return lookupMap
-}
-
-// processTypeWithImport processes protobuf type names, handling imported types and collecting import paths
-func processTypeWithImport(typeName string, file *descriptorpb.FileDescriptorProto, imports *[]string, allFiles []*descriptorpb.FileDescriptorProto, existingAliases map[string]bool) string {
-	typeName = strings.TrimPrefix(typeName, ".") // Remove the leading dot
-
-	parts := strings.Split(typeName, ".")
-	if len(parts) > 1 {
-		// Build lookup maps for efficient searching
-		packageLookup := buildPackageLookupMap(allFiles)
-		dependencyLookup := buildDependencyLookupMap(allFiles)
-
-		// Try to find the longest matching package name
-		for i := len(parts) - 1; i > 0; i-- {
-			importedPackage := strings.Join(parts[:i], ".")
-			localTypeName := parts[i]
-
-			// Check if the type is from the same package
-			if importedPackage == file.GetPackage() {
-				// Same package, return just the type name
-				return util.ToUpper(localTypeName)
-			}
-
-			// Check if the package exists in our lookup map
-			if depFile, exists := packageLookup[importedPackage]; exists {
-				// Verify this package is actually a dependency of the current file
-				for _, dep := range file.GetDependency() {
-					if depFile.GetName() == dep {
-						importPath := findImportPathFromDependency(dep, dependencyLookup)
-						found := false
-						for _, imp := range *imports {
-							if imp == importPath {
-								found = true
-								break
-							}
-						}
-						if !found {
-							*imports = append(*imports, importPath)
-						}
-
-						// Use package name from go_package option for consistent type references
-						goPackage := depFile.Options.GetGoPackage()
-						if goPackage != "" {
-							parts := strings.Split(goPackage, ";")
-							if len(parts) >= 2 {
-								// Use the package name from go_package option
-								return parts[1] + "." + localTypeName
-							}
-						}
-						// Fallback: use the last part of import path
-						importPathParts := strings.Split(importPath, "/")
-						packageName := importPathParts[len(importPathParts)-1]
-						return packageName + "." + localTypeName
-					}
-				}
-			}
-		}
-	}
-	// For local types, use the original logic
-	return util.ToUpper(parts[len(parts)-1])
-}
-
-// findImportPathFromDependency extracts the Go import path from a dependency file's go_package option
-func findImportPathFromDependency(depPath string, dependencyLookup map[string]*descriptorpb.FileDescriptorProto) string {
-	if depFile, exists := dependencyLookup[depPath]; exists {
-		goPackage := depFile.Options.GetGoPackage()
-		if goPackage != "" {
-			parts := strings.Split(goPackage, ";")
-			if len(parts) >= 1 {
-				return parts[0]
-			}
-		}
-		return strings.TrimSuffix(depPath, ".proto")
-	}
-	return strings.TrimSuffix(depPath, ".proto")
-}
-
-func (g *Generator) parseTripleToString(t TripleGo) (string, error) {
-	var builder strings.Builder
-
-	for _, tpl := range Tpls {
-		err := tpl.Execute(&builder, t)
-		if err != nil {
-			return "", err
-		}
-	}
-
-	return builder.String(), nil
-}
-
 func (g *Generator) generateToFile(filePath string, data []byte) error {
 	err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm)
 	if err != nil {
@@ -151,53 +45,152 @@ func (g *Generator) generateToFile(filePath string, data []byte) error {
 	return util.GoFmtFile(filePath)
 }
 
-func ProcessProtoFile(file *descriptorpb.FileDescriptorProto, allFiles []*descriptorpb.FileDescriptorProto) (TripleGo, error) {
+// ProcessProtoFile describes the triple stubs of file. The other files of plugin
+// provide the messages its options refer to.
+func ProcessProtoFile(plugin *protogen.Plugin, file *protogen.File) (TripleGo, error) {
+	return processProtoFile(plugin, file, true)
+}
+
+// processProtoFile is ProcessProtoFile. Unless strict is set, options that
+// cannot be read are left out instead of failing: the file is not generated in
+// this run, and only the identifiers of its stubs are of interest.
+func processProtoFile(plugin *protogen.Plugin, file *protogen.File, strict bool) (TripleGo, error) {
 	tripleGo := TripleGo{
-		Source:       file.GetName(),
-		ProtoPackage: file.GetPackage(),
+		Source:       file.Desc.Path(),
+		ProtoPackage: string(file.Desc.Package()),
+		Descriptor:   file.GoDescriptorIdent,
 		Services:     make([]Service, 0),
-		Imports:      make([]string, 0), // Added to collect imports
+		Deprecated:   file.Desc.Options().(*descriptorpb.FileOptions).GetDeprecated(),
 	}
 
-	// Track existing aliases to avoid conflicts
-	existingAliases := make(map[string]bool)
-	for _, service := range file.GetService() {
+	for _, service := range file.Services {
+		fullName := string(service.Desc.FullName())
+		if !serviceSelected(fullName) {
+			continue
+		}
 		serviceMethods := make([]Method, 0)
+		// Everything declared in a deprecated file or service is deprecated as well.
+		serviceDeprecated := tripleGo.Deprecated || service.Desc.Options().(*descriptorpb.ServiceOptions).GetDeprecated()
 
-		for _, method := range service.GetMethod() {
+		for _, method := range service.Methods {
+			if !methodSelected(fullName, string(method.Desc.Name())) {
+				continue
+			}
+			rules, err := httpRules(method)
+			if err != nil && strict {
+				return tripleGo, err
+			}
+			options, err := methodOptions(method)
+			if err != nil && strict {
+				return tripleGo, err
+			}
+			errs, err := methodErrors(plugin, method)
+			if err != nil && strict {
+				return tripleGo, err
+			}
 			serviceMethods = append(serviceMethods, Method{
-				MethodName:     method.GetName(),
-				RequestType:    processTypeWithImport(method.GetInputType(), file, &tripleGo.Imports, allFiles, existingAliases),
-				StreamsRequest: method.GetClientStreaming(),
-				ReturnType:     processTypeWithImport(method.GetOutputType(), file, &tripleGo.Imports, allFiles, existingAliases),
-				StreamsReturn:  method.GetServerStreaming(),
+				MethodName:       string(method.Desc.Name()),
+				GoName:           method.GoName,
+				Input:            method.Input,
+				Output:           method.Output,
+				HTTPRules:        rules,
+				Options:          options,
+				Errors:           errs,
+				IdempotencyLevel: method.Desc.Options().(*descriptorpb.MethodOptions).GetIdempotencyLevel(),
+				RequestType:      method.Input.GoIdent,
+				StreamsRequest:   method.Desc.IsStreamingClient(),
+				ReturnType:       method.Output.GoIdent,
+				StreamsReturn:    method.Desc.IsStreamingServer(),
+				Comments:         method.Comments,
+				Location:         method.Location,
+				Deprecated:       serviceDeprecated || method.Desc.Options().(*descriptorpb.MethodOptions).GetDeprecated(),
 			})
-			if method.GetClientStreaming() || method.GetServerStreaming() {
-				tripleGo.IsStream = true
-			}
+		}
+
+		// A service whose methods are all filtered out is left out altogether.
+		if len(service.Methods) > 0 && len(serviceMethods) == 0 {
+			continue
+		}
+		options, err := serviceOptions(service)
+		if err != nil && strict {
+			return tripleGo, err
 		}
 
 		tripleGo.Services = append(tripleGo.Services, Service{
-			ServiceName: service.GetName(),
-			Methods:     serviceMethods,
+			ServiceName:   string(service.Desc.Name()),
+			GoName:        service.GoName,
+			FullName:      fullName,
+			InterfaceName: interfaceName(file, service),
+			Methods:       serviceMethods,
+			Options:       options,
+			Comments:      service.Comments,
+			Location:      service.Location,
+			Deprecated:    serviceDeprecated,
 		})
 	}
+	nameErrors(tripleGo.Services)
 	// Package name will be set by main.go using file.GoPackageName
 	// to ensure consistency with protoc-gen-go
-	_, fileName := filepath.Split(file.GetName())
+	_, fileName := filepath.Split(file.Desc.Path())
 	tripleGo.FileName = strings.Split(fileName, ".")[0]
 	return tripleGo, nil
 }
 
-func GenTripleFile(genFile *protogen.GeneratedFile, triple TripleGo) error {
-	g := &Generator{}
-	data, err := g.parseTripleToString(triple)
-	if err != nil {
-		return err
+// GoPackage returns the name and import path of the Go package the triple stubs
+// of file are generated into. That is the package of the .pb.go file, unless a
+// package suffix is set: then it is a sub-package named after the message
+// package plus the suffix, which imports the messages like any other package.
+func GoPackage(file *protogen.File) (protogen.GoPackageName, protogen.GoImportPath) {
+	if *PackageSuffix == "" {
+		return file.GoPackageName, file.GoImportPath
+	}
+	name := file.GoPackageName + protogen.GoPackageName(*PackageSuffix)
+	return name, protogen.GoImportPath(path.Join(string(file.GoImportPath), string(name)))
+}
+
+// GeneratedFilenamePrefix is file.GeneratedFilenamePrefix, moved into the
+// directory of the sub-package when a package suffix is set.
+func GeneratedFilenamePrefix(file *protogen.File) string {
+	if *PackageSuffix == "" {
+		return file.GeneratedFilenamePrefix
 	}
+	name, _ := GoPackage(file)
+	return path.Join(path.Dir(file.GeneratedFilenamePrefix), string(name), path.Base(file.GeneratedFilenamePrefix))
+}
 
-	_, err = genFile.Write([]byte(data))
-	return err
+// TripleFile is a file to generate together with the stubs it holds.
+type TripleFile struct {
+	Filename string
+	TripleGo TripleGo
+}
+
+// SplitFiles returns the files the stubs of file are written to: a single one
+// named after the proto file, or one per service named after the service when
+// PerService is set. Every file gets its own header and imports.
+func SplitFiles(file *protogen.File, triple TripleGo) []TripleFile {
+	prefix := GeneratedFilenamePrefix(file)
+	if !*PerService {
+		return []TripleFile{{Filename: prefix + *FileSuffix, TripleGo: triple}}
+	}
+	files := make([]TripleFile, 0, len(triple.Services))
+	for _, s := range triple.Services {
+		t := triple
+		t.Services = []Service{s}
+		files = append(files, TripleFile{
+			Filename: path.Join(path.Dir(prefix), util.ToSnakeCase(s.GoName)+*FileSuffix),
+			TripleGo: t,
+		})
+	}
+	return files
+}
+
+// GenTripleFile writes the triple stubs described by triple into genFile. Every
+// identifier from another Go package goes through genFile.QualifiedGoIdent, so
+// protogen takes care of the import block and of aliasing clashing package names.
+func GenTripleFile(genFile *protogen.GeneratedFile, triple TripleGo) error {
+	g := &Generator{}
+	g.generate(genFile, triple)
+	return nil
 }
 
 type TripleGo struct {
@@ -205,115 +198,61 @@ type TripleGo struct {
 	Package      string
 	FileName     string
 	ProtoPackage string
-	Services     []Service
-	IsStream     bool
-	Imports      []string
+	// Descriptor is the File_* variable protoc-gen-go declares for the file.
+	Descriptor protogen.GoIdent
+	Services   []Service
+	Deprecated bool
 }
 
 type Service struct {
+	// ServiceName is the name declared in the .proto file and is what goes on the
+	// wire, GoName is the CamelCased form protogen derives for Go identifiers.
 	ServiceName string
-	Methods     []Method
+	GoName      string
+	// FullName is the package-qualified service name, or just ServiceName when
+	// the file declares no package. It names the service on the wire.
+	FullName string
+	// InterfaceName is the name the service is registered and referenced under in
+	// the dubbo registry. It is FullName unless the interface_name parameter or the
+	// dubbo.service option select another one, and only goes into the metadata:
+	// the wire path stays on FullName.
+	InterfaceName string
+	Methods       []Method
+	// Options are the defaults declared with the dubbo.service option.
+	Options DubboOptions
+	// ErrorDetails are the detail messages of declared errors the As{Detail}
+	// extractors are generated for along with the service.
+	ErrorDetails []ErrorDetail
+	// Comments and Location point back at the service in the .proto source.
+	Comments   protogen.CommentSet
+	Location   protogen.Location
+	Deprecated bool
 }
 
 type Method struct {
+	// MethodName is the name declared in the .proto file and is what goes on the
+	// wire, GoName is the CamelCased form protogen derives for Go identifiers.
 	MethodName     string
-	RequestType    string
+	GoName         string
+	RequestType    protogen.GoIdent
 	StreamsRequest bool
-	ReturnType     string
+	ReturnType     protogen.GoIdent
 	StreamsReturn  bool
-}
-
-// generateAlias creates a shorter, more readable alias for import paths to avoid package name conflicts
-// It tries to use meaningful parts of the import path and adds numbers when conflicts occur
-func generateAlias(importPath string, existingAliases map[string]bool) string {
-	// Split the import path into parts
-	parts := strings.Split(importPath, "/")
-
-	// Try different strategies to create a short, meaningful alias
-	candidates := []string{}
-
-	// Strategy 1: Use the last part (most common case)
-	if len(parts) > 0 {
-		lastPart := parts[len(parts)-1]
-		// Remove .proto extension if present
-		lastPart = strings.TrimSuffix(lastPart, ".proto")
-		candidates = append(candidates, lastPart)
-	}
-
-	// Strategy 2: Use the last two parts if they exist
-	if len(parts) >= 2 {
-		lastTwo := parts[len(parts)-2] + "_" + parts[len(parts)-1]
-		lastTwo = strings.TrimSuffix(lastTwo, ".proto")
-		candidates = append(candidates, lastTwo)
-	}
-
-	// Strategy 3: Use meaningful parts (skip common prefixes like "proto", "v1", etc.)
-	if len(parts) > 2 {
-		meaningfulParts := []string{}
-		for _, part := range parts {
-			// Skip common prefixes that don't add meaning
-			if part != "proto" && part != "v1" && part != "v2" && part != "v3" &&
-				part != "api" && part != "pkg" && part != "internal" && part != "external" {
-				meaningfulParts = append(meaningfulParts, part)
-			}
-		}
-		if len(meaningfulParts) > 0 {
-			meaningful := strings.Join(meaningfulParts, "_")
-			meaningful = strings.TrimSuffix(meaningful, ".proto")
-			candidates = append(candidates, meaningful)
-		}
-	}
-
-	// Strategy 4: Fallback to a shortened version of the full path
-	shortened := strings.ReplaceAll(strings.ReplaceAll(importPath, "/", "_"), ".", "_")
-	shortened = strings.TrimSuffix(shortened, ".proto")
-	// Limit length to avoid extremely long aliases
-	if len(shortened) > 30 {
-		shortened = shortened[:30]
-	}
-	candidates = append(candidates, shortened)
-
-	// Try each candidate, adding numbers if there are conflicts
-	for _, candidate := range candidates {
-		// Clean up the candidate (remove any invalid characters for Go identifiers)
-		candidate = strings.ReplaceAll(candidate, "-", "_")
-		candidate = strings.ReplaceAll(candidate, ".", "_")
-
-		// Ensure it starts with a letter or underscore
-		if len(candidate) > 0 && !((candidate[0] >= 'a' && candidate[0] <= 'z') ||
-			(candidate[0] >= 'A' && candidate[0] <= 'Z') || candidate[0] == '_') {
-			candidate = "pkg_" + candidate
-		}
-
-		// Try the candidate without number first
-		if !existingAliases[candidate] {
-			existingAliases[candidate] = true
-			return candidate
-		}
-
-		// If there's a conflict, try with numbers
-		for i := 1; i <= 999; i++ {
-			numberedCandidate := fmt.Sprintf("%s_%d", candidate, i)
-			if !existingAliases[numberedCandidate] {
-				existingAliases[numberedCandidate] = true
-				return numberedCandidate
-			}
-		}
-	}
-
-	// If all else fails, use a hash-based approach
-	hash := fmt.Sprintf("pkg_%x", importPath)
-	if len(hash) > 16 {
-		hash = hash[:16]
-	}
-
-	// Ensure uniqueness
-	counter := 1
-	finalHash := hash
-	for existingAliases[finalHash] {
-		finalHash = fmt.Sprintf("%s_%d", hash, counter)
-		counter++
-	}
-	existingAliases[finalHash] = true
-	return finalHash
+	// Input and Output are the request and response messages, RequestType and
+	// ReturnType their Go types.
+	Input  *protogen.Message
+	Output *protogen.Message
+	// HTTPRules are the REST bindings declared with the google.api.http option.
+	HTTPRules []HTTPRule
+	// Options are the call defaults declared with the dubbo.method option.
+	Options DubboOptions
+	// IdempotencyLevel tells whether the method has side effects, so that it can
+	// be retried.
+	IdempotencyLevel descriptorpb.MethodOptions_IdempotencyLevel
+	// Errors are the errors declared with the dubbo.method option.
+	Errors []MethodError
+	// Comments and Location point back at the method in the .proto source.
+	Comments   protogen.CommentSet
+	Location   protogen.Location
+	Deprecated bool
 }
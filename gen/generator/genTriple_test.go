@@ -0,0 +1,287 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+const greetV1Proto = `
+	name: "greet/v1/greet.proto"
+	package: "greet.v1"
+	options { go_package: "example.com/gen/greet/v1;greetv1" }
+	message_type { name: "GreetRequest" }
+	message_type { name: "GreetResponse" }
+	service {
+		name: "GreetService"
+		method { name: "Greet" input_type: ".greet.v1.GreetRequest" output_type: ".greet.v1.GreetResponse" }
+	}`
+
+func TestPackageSuffix(t *testing.T) {
+	tests := []struct {
+		name      string
+		params    []string
+		parameter string
+		// pkg and importPath are the Go package of the stubs, and prefix the
+		// prefix of their file name.
+		pkg, importPath, prefix string
+	}{
+		{
+			name:       "no suffix",
+			pkg:        "greetv1",
+			importPath: "example.com/gen/greet/v1",
+			prefix:     "example.com/gen/greet/v1/greet",
+		},
+		{
+			name:       "suffix",
+			params:     []string{"package_suffix=triple"},
+			pkg:        "greetv1triple",
+			importPath: "example.com/gen/greet/v1/greetv1triple",
+			prefix:     "example.com/gen/greet/v1/greetv1triple/greet",
+		},
+		{
+			name:       "suffix with source relative paths",
+			params:     []string{"package_suffix=triple"},
+			parameter:  "paths=source_relative",
+			pkg:        "greetv1triple",
+			importPath: "example.com/gen/greet/v1/greetv1triple",
+			prefix:     "greet/v1/greetv1triple/greet",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setParams(t, tt.params...)
+			if err := CheckOptions(); err != nil {
+				t.Fatal(err)
+			}
+			req := newRequest(t, greetV1Proto)
+			req.Parameter = &tt.parameter
+			plugin := newPlugin(t, req)
+			file := plugin.FilesByPath["greet/v1/greet.proto"]
+			pkg, importPath := GoPackage(file)
+			if string(pkg) != tt.pkg || string(importPath) != tt.importPath {
+				t.Errorf("GoPackage = %s %s, want %s %s", pkg, importPath, tt.pkg, tt.importPath)
+			}
+			if got := GeneratedFilenamePrefix(file); got != tt.prefix {
+				t.Errorf("GeneratedFilenamePrefix = %s, want %s", got, tt.prefix)
+			}
+
+			stubs := generate(t, plugin, "greet/v1/greet.proto")
+			if !strings.Contains(stubs, "\npackage "+tt.pkg+"\n") {
+				t.Errorf("stubs are not in package %s", tt.pkg)
+			}
+			// In the sub-package, the messages are imported from the package of
+			// the .pb.go files, named after the last element of its path.
+			sub := tt.pkg != "greetv1"
+			for _, want := range []string{
+				`v1 "example.com/gen/greet/v1"`,
+				"Greet(ctx context.Context, req *v1.GreetRequest, opts ...client.CallOption) (*v1.GreetResponse, error)",
+			} {
+				if strings.Contains(stubs, want) != sub {
+					t.Errorf("stubs contain %q: %v, want %v", want, !sub, sub)
+				}
+			}
+		})
+	}
+
+	setParams(t, "package_suffix=-triple")
+	if err := CheckOptions(); err == nil {
+		t.Error("CheckOptions accepted package_suffix=-triple")
+	}
+}
+
+func TestSplitFiles(t *testing.T) {
+	proto := `
+		name: "greet/v1/greet.proto"
+		package: "greet.v1"
+		options { go_package: "example.com/gen/greet/v1;greetv1" }
+		message_type { name: "GreetRequest" }
+		message_type { name: "GreetResponse" }
+		service {
+			name: "GreetService"
+			method { name: "Greet" input_type: ".greet.v1.GreetRequest" output_type: ".greet.v1.GreetResponse" }
+		}
+		service {
+			name: "AdminService"
+			method { name: "Reset" input_type: ".greet.v1.GreetRequest" output_type: ".greet.v1.GreetResponse" }
+		}`
+	tests := []struct {
+		name   string
+		params []string
+		// files maps the names of the generated files to the services in them.
+		files map[string][]string
+	}{
+		{
+			name: "per proto file",
+			files: map[string][]string{
+				"example.com/gen/greet/v1/greet.triple.go": {"GreetService", "AdminService"},
+			},
+		},
+		{
+			name:   "per service",
+			params: []string{"per_service=true"},
+			files: map[string][]string{
+				"example.com/gen/greet/v1/greet_service.triple.go": {"GreetService"},
+				"example.com/gen/greet/v1/admin_service.triple.go": {"AdminService"},
+			},
+		},
+		{
+			name:   "per service with file suffix",
+			params: []string{"per_service=true", "file_suffix=.dubbo.go"},
+			files: map[string][]string{
+				"example.com/gen/greet/v1/greet_service.dubbo.go": {"GreetService"},
+				"example.com/gen/greet/v1/admin_service.dubbo.go": {"AdminService"},
+			},
+		},
+		{
+			name:   "per service in a sub-package",
+			params: []string{"per_service=true", "package_suffix=triple"},
+			files: map[string][]string{
+				"example.com/gen/greet/v1/greetv1triple/greet_service.triple.go": {"GreetService"},
+				"example.com/gen/greet/v1/greetv1triple/admin_service.triple.go": {"AdminService"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setParams(t, tt.params...)
+			if err := CheckOptions(); err != nil {
+				t.Fatal(err)
+			}
+			plugin := newPlugin(t, newRequest(t, proto))
+			file := plugin.FilesByPath["greet/v1/greet.proto"]
+			tripleGo, err := ProcessProtoFile(plugin, file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pkg, importPath := GoPackage(file)
+			tripleGo.Package = string(pkg)
+			files := SplitFiles(file, tripleGo)
+			if len(files) != len(tt.files) {
+				t.Fatalf("SplitFiles returned %d files, want %d", len(files), len(tt.files))
+			}
+			for _, out := range files {
+				services, ok := tt.files[out.Filename]
+				if !ok {
+					t.Errorf("unexpected file %s", out.Filename)
+					continue
+				}
+				g := plugin.NewGeneratedFile(out.Filename, importPath)
+				if err := GenTripleFile(g, out.TripleGo); err != nil {
+					t.Fatal(err)
+				}
+				content, err := g.Content()
+				if err != nil {
+					t.Fatalf("%s does not parse: %v", out.Filename, err)
+				}
+				stubs := string(content)
+				// Every file has its own header and imports.
+				for _, want := range []string{
+					"// Source: greet/v1/greet.proto\n",
+					"\npackage " + string(pkg) + "\n",
+					`client "dubbo.apache.org/dubbo-go/v3/client"`,
+				} {
+					if !strings.Contains(stubs, want) {
+						t.Errorf("%s lacks %q", out.Filename, want)
+					}
+				}
+				for _, s := range []string{"GreetService", "AdminService"} {
+					want := false
+					for _, service := range services {
+						want = want || service == s
+					}
+					if got := strings.Contains(stubs, "type "+s+"Handler interface {"); got != want {
+						t.Errorf("%s declares %s: %v, want %v", out.Filename, s, got, want)
+					}
+				}
+			}
+		})
+	}
+
+	for _, suffix := range []string{".pb.go", ".triple"} {
+		setParams(t, "file_suffix="+suffix)
+		if err := CheckOptions(); err == nil {
+			t.Errorf("CheckOptions accepted file_suffix=%s", suffix)
+		}
+	}
+}
+
+func TestProcessProtoFileTypes(t *testing.T) {
+	setParams(t)
+	plugin := newPlugin(t, newRequest(t, `
+		name: "common/common.proto"
+		package: "common"
+		options { go_package: "example.com/gen/common;commonv1" }
+		message_type { name: "CommonRequest" }`, `
+		name: "greet/types.proto"
+		package: "greet.types"
+		options { go_package: "example.com/gen/greet" }
+		message_type { name: "Envelope" nested_type { name: "Payload" } }`, `
+		name: "greet/greet.proto"
+		package: "greet"
+		dependency: "common/common.proto"
+		dependency: "greet/types.proto"
+		options { go_package: "example.com/gen/greet" }
+		service {
+			name: "GreetService"
+			method { name: "Nested" input_type: ".greet.types.Envelope.Payload" output_type: ".greet.types.Envelope" }
+			method { name: "Common" input_type: ".common.CommonRequest" output_type: ".greet.types.Envelope.Payload" }
+		}`))
+	tripleGo, err := ProcessProtoFile(plugin, plugin.FilesByPath["greet/greet.proto"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nested messages keep their parents in their Go names, and the messages of
+	// another proto package in the same Go package are not imported.
+	types := map[string][2]protogen.GoIdent{
+		"Nested": {
+			{GoName: "Envelope_Payload", GoImportPath: "example.com/gen/greet"},
+			{GoName: "Envelope", GoImportPath: "example.com/gen/greet"},
+		},
+		"Common": {
+			{GoName: "CommonRequest", GoImportPath: "example.com/gen/common"},
+			{GoName: "Envelope_Payload", GoImportPath: "example.com/gen/greet"},
+		},
+	}
+	for _, m := range tripleGo.Services[0].Methods {
+		if got := [2]protogen.GoIdent{m.RequestType, m.ReturnType}; got != types[m.MethodName] {
+			t.Errorf("%s takes and returns %v, want %v", m.MethodName, got, types[m.MethodName])
+		}
+	}
+
+	stubs := generate(t, plugin, "greet/greet.proto")
+	for _, want := range []string{
+		"Nested(ctx context.Context, req *Envelope_Payload, opts ...client.CallOption) (*Envelope, error)",
+		"Common(ctx context.Context, req *common.CommonRequest, opts ...client.CallOption) (*Envelope_Payload, error)",
+		// protogen renames the dubbo-go package of the same name.
+		`common "example.com/gen/common"`,
+		`common1 "dubbo.apache.org/dubbo-go/v3/common"`,
+	} {
+		if !strings.Contains(stubs, want) {
+			t.Errorf("stubs lack %q", want)
+		}
+	}
+	if strings.Contains(stubs, `"example.com/gen/greet"`) {
+		t.Error("stubs import their own package")
+	}
+}
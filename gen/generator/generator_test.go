@@ -0,0 +1,144 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const greetMessages = `
+message_type { name: "GreetRequest" }
+message_type { name: "GreetResponse" }
+`
+
+// rpc declares a unary method of the messages in greetMessages.
+func rpc(name string) string {
+	return `method { name: "` + name + `" input_type: ".greet.GreetRequest" output_type: ".greet.GreetResponse" }`
+}
+
+// greetStreams declares a method of every stream type.
+const greetStreams = `
+service {
+	name: "GreetService"
+	method { name: "Greet" input_type: ".greet.GreetRequest" output_type: ".greet.GreetResponse" }
+	method { name: "GreetClient" input_type: ".greet.GreetRequest" output_type: ".greet.GreetResponse" client_streaming: true }
+	method { name: "GreetServer" input_type: ".greet.GreetRequest" output_type: ".greet.GreetResponse" server_streaming: true }
+	method { name: "GreetBidi" input_type: ".greet.GreetRequest" output_type: ".greet.GreetResponse" client_streaming: true server_streaming: true }
+}`
+
+// setParams sets the plugin parameters for the duration of t: the defaults main
+// binds them to, overridden by params in the key=value form protoc passes.
+func setParams(t *testing.T, params ...string) {
+	t.Helper()
+	warnDeprecated, requireUnimplemented, packageSuffix := WarnDeprecated, RequireUnimplemented, PackageSuffix
+	client, server, perService, fileSuffix := Client, Server, PerService, FileSuffix
+	mocks, openAPI, validate, interfaceName, iterators := Mocks, OpenAPI, Validate, InterfaceName, Iterators
+	inProcess, idempotentRetries := InProcess, IdempotentRetries
+	include, exclude := Include, Exclude
+	t.Cleanup(func() {
+		WarnDeprecated, RequireUnimplemented, PackageSuffix = warnDeprecated, requireUnimplemented, packageSuffix
+		Client, Server, PerService, FileSuffix = client, server, perService, fileSuffix
+		Mocks, OpenAPI, Validate, InterfaceName, Iterators = mocks, openAPI, validate, interfaceName, iterators
+		InProcess, IdempotentRetries = inProcess, idempotentRetries
+		Include, Exclude = include, exclude
+	})
+
+	var flags flag.FlagSet
+	RequireUnimplemented = flags.Bool("require_unimplemented_handlers", false, "")
+	WarnDeprecated = flags.Bool("warn_deprecated", false, "")
+	PackageSuffix = flags.String("package_suffix", "", "")
+	Client = flags.Bool("client", true, "")
+	Server = flags.Bool("server", true, "")
+	PerService = flags.Bool("per_service", false, "")
+	FileSuffix = flags.String("file_suffix", ".triple.go", "")
+	Mocks = flags.Bool("mocks", false, "")
+	OpenAPI = flags.String("openapi", "false", "")
+	Validate = flags.String("validate", "false", "")
+	InterfaceName = flags.String("interface_name", "proto", "")
+	Iterators = flags.String("iterators", "false", "")
+	InProcess = flags.Bool("inprocess", false, "")
+	IdempotentRetries = flags.Int("idempotent_retries", 0, "")
+	Include, Exclude = nil, nil
+	flags.Var(&Include, "include", "")
+	flags.Var(&Exclude, "exclude", "")
+	for _, param := range params {
+		key, value, _ := strings.Cut(param, "=")
+		if err := flags.Set(key, value); err != nil {
+			t.Fatalf("parameter %s: %v", param, err)
+		}
+	}
+}
+
+// newRequest returns a CodeGeneratorRequest for the files, which are
+// FileDescriptorProtos in text format, asking to generate all of them.
+func newRequest(t *testing.T, files ...string) *pluginpb.CodeGeneratorRequest {
+	t.Helper()
+	req := &pluginpb.CodeGeneratorRequest{}
+	for _, text := range files {
+		file := &descriptorpb.FileDescriptorProto{}
+		if err := prototext.Unmarshal([]byte(text), file); err != nil {
+			t.Fatalf("parsing file descriptor: %v\n%s", err, text)
+		}
+		req.ProtoFile = append(req.ProtoFile, file)
+		req.FileToGenerate = append(req.FileToGenerate, file.GetName())
+	}
+	return req
+}
+
+func newPlugin(t *testing.T, req *pluginpb.CodeGeneratorRequest) *protogen.Plugin {
+	t.Helper()
+	plugin, err := protogen.Options{}.New(req)
+	if err != nil {
+		t.Fatalf("protogen: %v", err)
+	}
+	return plugin
+}
+
+// generate returns the triple stubs of the file named name in plugin, formatted
+// by protogen, which fails on code that does not parse.
+func generate(t *testing.T, plugin *protogen.Plugin, name string) string {
+	t.Helper()
+	file, ok := plugin.FilesByPath[name]
+	if !ok {
+		t.Fatalf("no file %s", name)
+	}
+	tripleGo, err := ProcessProtoFile(plugin, file)
+	if err != nil {
+		t.Fatalf("ProcessProtoFile: %v", err)
+	}
+	packageName, importPath := GoPackage(file)
+	tripleGo.Package = string(packageName)
+	g := plugin.NewGeneratedFile(GeneratedFilenamePrefix(file)+*FileSuffix, importPath)
+	if err := GenTripleFile(g, tripleGo); err != nil {
+		t.Fatalf("GenTripleFile: %v", err)
+	}
+	content, err := g.Content()
+	if err != nil {
+		t.Fatalf("generated code of %s does not parse: %v", name, err)
+	}
+	return string(content)
+}
@@ -0,0 +1,167 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata/golden")
+
+// TestGolden generates the stubs of the fixtures of test/correctly, whose
+// CodeGeneratorRequests are kept in testdata/golden, and compares them with the
+// golden files next to them. The golden files started as the output of the
+// tripleTpl.go template the emitter replaced. Run go test -update to rewrite
+// them after changing the generated code.
+func TestGolden(t *testing.T) {
+	for _, fixture := range []string{"one_service", "multiple_services", "import_nested"} {
+		t.Run(fixture, func(t *testing.T) {
+			setParams(t)
+			dir := filepath.Join("testdata", "golden", fixture)
+			text, err := os.ReadFile(filepath.Join(dir, "request.textproto"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := &pluginpb.CodeGeneratorRequest{}
+			if err := prototext.Unmarshal(text, req); err != nil {
+				t.Fatalf("parsing request: %v", err)
+			}
+			plugin := newPlugin(t, req)
+			for _, name := range req.FileToGenerate {
+				got := generate(t, plugin, name)
+				golden := filepath.Join(dir, strings.TrimSuffix(path.Base(name), ".proto")+".triple.go.golden")
+				if *update {
+					if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, want := normalize(t, plugin, got), normalize(t, plugin, string(want)); got != want {
+					t.Errorf("stubs of %s differ from %s:\n%s", name, golden, firstDiff(got, want))
+				}
+			}
+		})
+	}
+}
+
+// normalize returns src without its imports, with the packages it refers to
+// written as their import paths and without blank lines, so that code differing
+// only in the names of its imports or in the grouping of its declarations
+// compares equal. protogen names every import, tripleTpl.go named none.
+func normalize(t *testing.T, plugin *protogen.Plugin, src string) string {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("parsing generated code: %v", err)
+	}
+	paths := map[string]string{}
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		name := packageName(plugin, importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		paths[name] = spec.Path.Value
+	}
+	var decls []ast.Decl
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			continue
+		}
+		decls = append(decls, decl)
+	}
+	file.Decls, file.Imports = decls, nil
+	ast.Inspect(file, func(n ast.Node) bool {
+		// Identifiers the parser resolved are declared in the file, so they are
+		// not package names even where they look like one.
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok && x.Obj == nil && paths[x.Name] != "" {
+				x.Name = paths[x.Name]
+			}
+		}
+		return true
+	})
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		t.Fatalf("printing generated code: %v", err)
+	}
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// packageName returns the name of the package imported from importPath without
+// a name: that of the generated files of the request, and otherwise the last
+// element of the path, but for dubbo-go whose module path ends in its version.
+func packageName(plugin *protogen.Plugin, importPath string) string {
+	for _, file := range plugin.Files {
+		if string(file.GoImportPath) == importPath {
+			return string(file.GoPackageName)
+		}
+	}
+	if importPath == "dubbo.apache.org/dubbo-go/v3" {
+		return "dubbo"
+	}
+	return path.Base(importPath)
+}
+
+// firstDiff returns the lines around the first line got and want differ in.
+func firstDiff(got, want string) string {
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(want, "\n")
+	i := 0
+	for i < len(gotLines) && i < len(wantLines) && gotLines[i] == wantLines[i] {
+		i++
+	}
+	around := func(lines []string) string {
+		from, to := i-3, i+3
+		if from < 0 {
+			from = 0
+		}
+		if to > len(lines) {
+			to = len(lines)
+		}
+		return strings.Join(lines[from:to], "\n")
+	}
+	return "got:\n" + around(gotLines) + "\nwant:\n" + around(wantLines)
+}
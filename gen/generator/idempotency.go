@@ -0,0 +1,82 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"strconv"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// idempotencyMeta is the Meta key of the idempotency level of a method, a
+// triple_protocol.IdempotencyLevel.
+const idempotencyMeta = "idempotency_level"
+
+// idempotencyIdent returns the triple_protocol constant of the idempotency level
+// of m, or false when it is unknown.
+func idempotencyIdent(m Method) (protogen.GoIdent, bool) {
+	switch m.IdempotencyLevel {
+	case descriptorpb.MethodOptions_NO_SIDE_EFFECTS:
+		return tripleProtocolPackage.Ident("IdempotencyNoSideEffects"), true
+	case descriptorpb.MethodOptions_IDEMPOTENT:
+		return tripleProtocolPackage.Ident("IdempotencyIdempotent"), true
+	}
+	return protogen.GoIdent{}, false
+}
+
+// genIdempotencyMeta writes the idempotency level of m into its MethodInfo Meta.
+func genIdempotencyMeta(g *protogen.GeneratedFile, m Method) {
+	if level, ok := idempotencyIdent(m); ok {
+		g.P(strconv.Quote(idempotencyMeta), ": ", level, ",")
+	}
+}
+
+// genClientIdempotencyMeta writes the idempotency levels of the methods of s into
+// the Meta of its ClientInfo, keyed by method name, so that retry policies can
+// tell which calls are safe to repeat.
+func genClientIdempotencyMeta(g *protogen.GeneratedFile, s Service) {
+	if !hasIdempotentMethods(s) {
+		return
+	}
+	g.P(strconv.Quote(idempotencyMeta), ": map[string]", tripleProtocolPackage.Ident("IdempotencyLevel"), "{")
+	for _, m := range s.Methods {
+		if level, ok := idempotencyIdent(m); ok {
+			g.P(strconv.Quote(m.MethodName), ": ", level, ",")
+		}
+	}
+	g.P("},")
+}
+
+func hasIdempotentMethods(s Service) bool {
+	for _, m := range s.Methods {
+		if _, ok := idempotencyIdent(m); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// retriesByDefault reports whether calls to m are retried IdempotentRetries
+// times: it is unary, safe to repeat and neither s nor m sets the retries.
+func retriesByDefault(s Service, m Method) bool {
+	_, ok := idempotencyIdent(m)
+	return *IdempotentRetries > 0 && ok && !m.isStream() && m.Options.Retries == nil && s.Options.Retries == nil
+}
@@ -0,0 +1,110 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGenIdempotency(t *testing.T) {
+	setParams(t)
+	req := newRequest(t, `
+		name: "greet.proto"
+		package: "greet"
+		options { go_package: "example.com/greet" }
+		`+greetMessages+`
+		service {
+			name: "GreetService"
+			method {
+				name: "Get" input_type: ".greet.GreetRequest" output_type: ".greet.GreetResponse"
+				options { idempotency_level: NO_SIDE_EFFECTS }
+			}
+			method {
+				name: "Put" input_type: ".greet.GreetRequest" output_type: ".greet.GreetResponse"
+				options { idempotency_level: IDEMPOTENT }
+			}
+			method {
+				name: "PutOnce" input_type: ".greet.GreetRequest" output_type: ".greet.GreetResponse"
+				options { idempotency_level: IDEMPOTENT [dubbo.method] { retries: 0 } }
+			}
+			method {
+				name: "Watch" input_type: ".greet.GreetRequest" output_type: ".greet.GreetResponse" server_streaming: true
+				options { idempotency_level: NO_SIDE_EFFECTS }
+			}
+			`+rpc("Post")+`
+		}`)
+	stubs := generate(t, newPlugin(t, req), "greet.proto")
+
+	for _, want := range []string{
+		`"idempotency_level": triple_protocol.IdempotencyNoSideEffects,`,
+		`"idempotency_level": triple_protocol.IdempotencyIdempotent,`,
+		`"Get":     triple_protocol.IdempotencyNoSideEffects,`,
+	} {
+		if !strings.Contains(stubs, want) {
+			t.Errorf("generated stubs lack %q", want)
+		}
+	}
+	for _, key := range []string{"handler_options", "client_options"} {
+		if strings.Contains(stubs, strconv.Quote(key)) {
+			t.Errorf("generated stubs declare the %s Meta nobody reads", key)
+		}
+	}
+	// The dubbo server only serves POST, so nothing is sent with GET.
+	for _, unwanted := range []string{"TripleHandler", "HTTPGet", "c.get["} {
+		if strings.Contains(stubs, unwanted) {
+			t.Errorf("generated stubs contain %q", unwanted)
+		}
+	}
+	// Retries are opt-in.
+	if strings.Contains(stubs, "client.WithCallRetries(2)") {
+		t.Error("idempotent methods are retried without idempotent_retries")
+	}
+
+	// With idempotent_retries, unary calls safe to repeat are retried unless
+	// the retries are set.
+	setParams(t, "idempotent_retries=2")
+	stubs = generate(t, newPlugin(t, req), "greet.proto")
+	for method, want := range map[string]string{
+		"Get":     "client.WithCallRetries(2)",
+		"Put":     "client.WithCallRetries(2)",
+		"PutOnce": "client.WithCallRetries(0)",
+		"Watch":   "",
+		"Post":    "",
+	} {
+		start := strings.Index(stubs, "func (c *GreetServiceImpl) "+method+"(")
+		if start < 0 {
+			t.Fatalf("generated stubs lack the %s client method", method)
+		}
+		body := stubs[start:]
+		body = body[:strings.Index(body, "\n}\n")]
+		if got := strings.Count(body, "WithCallRetries"); want == "" && got != 0 {
+			t.Errorf("%s is retried:\n%s", method, body)
+		} else if want != "" && (got != 1 || !strings.Contains(body, want)) {
+			t.Errorf("%s lacks %s:\n%s", method, want, body)
+		}
+	}
+}
+
+func TestIdempotentRetriesOption(t *testing.T) {
+	setParams(t, "idempotent_retries=-1")
+	if err := CheckOptions(); err == nil {
+		t.Error("CheckOptions accepted idempotent_retries=-1")
+	}
+}
@@ -0,0 +1,714 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"strconv"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// genInProcess writes New{Service}InProcess, a client that calls a handler in
+// the same process. Unary calls go straight to the handler, streaming calls run
+// the handler in a goroutine connected to the client through channels. Messages
+// are copied on their way through, so that neither side sees the other modify
+// them, as with a remote call.
+func genInProcess(g *protogen.GeneratedFile, t TripleGo) {
+	for _, s := range t.Services {
+		client := inProcessClient(s)
+		g.P("// New", s.GoName, "InProcess returns a ", s.GoName, " that dispatches every call directly to")
+		g.P("// handler, without a network connection or a dubbo server. It is meant for tests of")
+		g.P("// code consuming the ", s.FullName, " service. Streams must be closed, or the context of")
+		g.P("// their call cancelled, to stop the goroutine running the handler. Close does it")
+		g.P("// for all the streams still open.")
+		if s.Deprecated {
+			g.P("//")
+			g.P(deprecationComment)
+		}
+		g.P("func New", s.GoName, "InProcess(handler ", s.GoName, "Handler) *", client, " {")
+		g.P("return &", client, "{handler: handler}")
+		g.P("}")
+		g.P()
+		g.P("// ", client, " is the ", s.GoName, " returned by New", s.GoName, "InProcess.")
+		g.P("type ", client, " struct {")
+		g.P("handler ", s.GoName, "Handler")
+		g.P()
+		g.P("mu ", syncPackage.Ident("Mutex"))
+		g.P("closed bool")
+		if hasStream(s) {
+			g.P("streams map[*", inProcessStream(s), "]struct{}")
+		}
+		g.P("}")
+		g.P()
+		g.P("var _ ", s.GoName, " = (*", client, ")(nil)")
+		g.P()
+		for _, m := range s.Methods {
+			genInProcessCall(g, s, m)
+		}
+		genInProcessClose(g, s)
+		genInProcessError(g, s)
+		genInProcessTimeout(g, s)
+		if hasStream(s) {
+			genInProcessStream(g, s)
+			genInProcessConn(g, s)
+		}
+		for _, m := range s.Methods {
+			if m.isStream() {
+				genInProcessStreamClient(g, s, m)
+				genInProcessStreamServer(g, s, m)
+			}
+		}
+	}
+}
+
+func genInProcessCall(g *protogen.GeneratedFile, s Service, m Method) {
+	stream := inProcessStream(s)
+	clone := protoPackage.Ident("Clone")
+	g.P("func (c *", inProcessClient(s), ") ", m.GoName, clientSignature(g, s, m), " {")
+	genCallDefaults(g, s, m)
+	g.P("timeout, err := ", inProcessTimeout(s), "(opts)")
+	g.P("if err != nil {")
+	g.P("return nil, err")
+	g.P("}")
+	if !m.isStream() {
+		// Streams check it when they are tracked.
+		g.P("if err := c.open(); err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+	}
+	if !m.StreamsRequest {
+		g.P("if err := ctx.Err(); err != nil {")
+		g.P("return nil, ", inProcessError(s), "(err)")
+		g.P("}")
+		if validateHandler() && m.validates() {
+			genHandlerValidateCall(g, s, "c.handler", "req")
+		}
+	}
+	if !m.isStream() {
+		g.P("if timeout > 0 {")
+		g.P("var cancel ", contextPackage.Ident("CancelFunc"))
+		g.P("ctx, cancel = ", contextPackage.Ident("WithTimeout"), "(ctx, timeout)")
+		g.P("defer cancel()")
+		g.P("}")
+		g.P("res, err := c.handler.", m.GoName, "(ctx, ", clone, "(req).(*", m.RequestType, "))")
+		g.P("if err != nil {")
+		g.P("return nil, ", inProcessHandlerError(s), "(err)")
+		g.P("}")
+		g.P("return ", clone, "(res).(*", m.ReturnType, "), nil")
+		g.P("}")
+		g.P()
+		return
+	}
+	streamType := "StreamTypeBidi"
+	switch {
+	case !m.StreamsReturn:
+		streamType = "StreamTypeClient"
+	case !m.StreamsRequest:
+		streamType = "StreamTypeServer"
+	}
+	g.P("stream := new", s.GoName, "InProcessStream(ctx, timeout, ", s.GoName, m.GoName, "Procedure, ", tripleProtocolPackage.Ident(streamType), ")")
+	g.P("if err := c.track(stream); err != nil {")
+	g.P("return nil, err")
+	g.P("}")
+	g.P("stream.start(func(ctx ", contextPackage.Ident("Context"), ") (", protoPackage.Ident("Message"), ", error) {")
+	srv := "&" + inProcessStreamServer(s, m) + "{" + stream + ": stream}"
+	switch {
+	case m.StreamsRequest && m.StreamsReturn:
+		g.P("return nil, c.handler.", m.GoName, "(ctx, ", srv, ")")
+	case m.StreamsRequest:
+		g.P("return c.handler.", m.GoName, "(ctx, ", srv, ")")
+	default:
+		g.P("return nil, c.handler.", m.GoName, "(ctx, ", clone, "(req).(*", m.RequestType, "), ", srv, ")")
+	}
+	g.P("})")
+	g.P("return &", inProcessStreamClient(s, m), "{", stream, ": stream}, nil")
+	g.P("}")
+	g.P()
+}
+
+// genInProcessClose writes Close and the bookkeeping of the streams it stops.
+func genInProcessClose(g *protogen.GeneratedFile, s Service) {
+	client := inProcessClient(s)
+	g.P("// Close cancels the streams still open and fails the calls made after it.")
+	g.P("func (c *", client, ") Close() error {")
+	g.P("c.mu.Lock()")
+	g.P("defer c.mu.Unlock()")
+	g.P("c.closed = true")
+	if hasStream(s) {
+		g.P("for stream := range c.streams {")
+		g.P("stream.cancel()")
+		g.P("}")
+		g.P("c.streams = nil")
+	}
+	g.P("return nil")
+	g.P("}")
+	g.P()
+	g.P("func (c *", client, ") open() error {")
+	g.P("c.mu.Lock()")
+	g.P("defer c.mu.Unlock()")
+	g.P("return c.checkOpen()")
+	g.P("}")
+	g.P()
+	g.P("func (c *", client, ") checkOpen() error {")
+	g.P("if c.closed {")
+	g.P("return ", tripleProtocolPackage.Ident("NewError"), "(", tripleProtocolPackage.Ident("CodeCanceled"), ", ",
+		errorsPackage.Ident("New"), "(", strconv.Quote("in-process client is closed"), "))")
+	g.P("}")
+	g.P("return nil")
+	g.P("}")
+	g.P()
+	if !hasStream(s) {
+		return
+	}
+	stream := inProcessStream(s)
+	g.P("// track records stream until it ends, for Close to cancel it.")
+	g.P("func (c *", client, ") track(stream *", stream, ") error {")
+	g.P("c.mu.Lock()")
+	g.P("defer c.mu.Unlock()")
+	g.P("if err := c.checkOpen(); err != nil {")
+	g.P("stream.cancel()")
+	g.P("return err")
+	g.P("}")
+	g.P("if c.streams == nil {")
+	g.P("c.streams = make(map[*", stream, "]struct{})")
+	g.P("}")
+	g.P("c.streams[stream] = struct{}{}")
+	g.P("go func() {")
+	g.P("<-stream.ctx.Done()")
+	g.P("c.mu.Lock()")
+	g.P("delete(c.streams, stream)")
+	g.P("c.mu.Unlock()")
+	g.P("}()")
+	g.P("return nil")
+	g.P("}")
+	g.P()
+}
+
+// genInProcessError writes the conversion of context and handler errors into
+// the triple errors a remote call would have failed with.
+func genInProcessError(g *protogen.GeneratedFile, s Service) {
+	g.P("func ", inProcessError(s), "(err error) error {")
+	g.P("if ", errorsPackage.Ident("Is"), "(err, ", contextPackage.Ident("DeadlineExceeded"), ") {")
+	g.P("return ", tripleProtocolPackage.Ident("NewError"), "(", tripleProtocolPackage.Ident("CodeDeadlineExceeded"), ", err)")
+	g.P("}")
+	g.P("return ", tripleProtocolPackage.Ident("NewError"), "(", tripleProtocolPackage.Ident("CodeCanceled"), ", err)")
+	g.P("}")
+	g.P()
+	g.P("// ", inProcessHandlerError(s), " returns the error of a handler as the caller of a remote")
+	g.P("// call gets it: triple errors are kept, context errors become CodeCanceled or")
+	g.P("// CodeDeadlineExceeded and any other error CodeUnknown.")
+	g.P("func ", inProcessHandlerError(s), "(err error) error {")
+	g.P("var tripleErr *", tripleProtocolPackage.Ident("Error"))
+	g.P("if ", errorsPackage.Ident("As"), "(err, &tripleErr) {")
+	g.P("return err")
+	g.P("}")
+	g.P("if ", errorsPackage.Ident("Is"), "(err, ", contextPackage.Ident("Canceled"), ") || ", errorsPackage.Ident("Is"), "(err, ", contextPackage.Ident("DeadlineExceeded"), ") {")
+	g.P("return ", inProcessError(s), "(err)")
+	g.P("}")
+	g.P("return ", tripleProtocolPackage.Ident("NewError"), "(", tripleProtocolPackage.Ident("CodeUnknown"), ", err)")
+	g.P("}")
+	g.P()
+}
+
+// genInProcessTimeout writes the function reading the request timeout from the
+// CallOptions of an in-process call.
+func genInProcessTimeout(g *protogen.GeneratedFile, s Service) {
+	g.P("// ", inProcessTimeout(s), " returns the request timeout opts set, or 0 for none. Retries")
+	g.P("// do not apply to in-process calls, which have no transport to fail.")
+	g.P("func ", inProcessTimeout(s), "(opts []", clientPackage.Ident("CallOption"), ") (", timePackage.Ident("Duration"), ", error) {")
+	g.P("var callOpts ", clientPackage.Ident("CallOptions"))
+	g.P("for _, opt := range opts {")
+	g.P("opt(&callOpts)")
+	g.P("}")
+	g.P("if callOpts.RequestTimeout == \"\" {")
+	g.P("return 0, nil")
+	g.P("}")
+	g.P("timeout, err := ", timePackage.Ident("ParseDuration"), "(callOpts.RequestTimeout)")
+	g.P("if err != nil {")
+	g.P("return 0, ", tripleProtocolPackage.Ident("NewError"), "(", tripleProtocolPackage.Ident("CodeInvalidArgument"), ", err)")
+	g.P("}")
+	g.P("return timeout, nil")
+	g.P("}")
+	g.P()
+}
+
+// inProcessBuffer is the number of messages each direction of an in-process
+// stream holds before Send blocks, like the flow control window of a network
+// stream: a client can send several messages before receiving the responses of
+// an echoing handler.
+const inProcessBuffer = 16
+
+// genInProcessStream writes the pipe shared by the in-process streams of s.
+// Each direction is a buffered channel, so neither side waits for the other to
+// receive before sending on. Messages still buffered when the handler returns or
+// the client closes its side are received before the end of the stream. The
+// handler runs with a context derived from the one of the call, which is
+// cancelled when the client closes the stream.
+func genInProcessStream(g *protogen.GeneratedFile, s Service) {
+	stream := inProcessStream(s)
+	header := httpPackage.Ident("Header")
+	g.P("// ", stream, " connects an in-process client stream to the handler serving it.")
+	g.P("type ", stream, " struct {")
+	g.P("ctx ", contextPackage.Ident("Context"))
+	g.P("cancel ", contextPackage.Ident("CancelFunc"))
+	g.P("spec ", tripleProtocolPackage.Ident("Spec"))
+	g.P("reqs chan ", protoPackage.Ident("Message"))
+	g.P("resps chan ", protoPackage.Ident("Message"))
+	g.P("reqClosed chan struct{}")
+	g.P("closeReq ", syncPackage.Ident("Once"))
+	g.P("reqHeader ", header)
+	g.P("respHeader ", header)
+	g.P("respTrailer ", header)
+	g.P()
+	g.P("// done is closed when the handler returned resp and err.")
+	g.P("done chan struct{}")
+	g.P("resp ", protoPackage.Ident("Message"))
+	g.P("err error")
+	g.P("}")
+	g.P()
+	g.P("func new", s.GoName, "InProcessStream(ctx ", contextPackage.Ident("Context"), ", timeout ", timePackage.Ident("Duration"), ", procedure string, streamType ", tripleProtocolPackage.Ident("StreamType"), ") *", stream, " {")
+	g.P("var cancel ", contextPackage.Ident("CancelFunc"))
+	g.P("if timeout > 0 {")
+	g.P("ctx, cancel = ", contextPackage.Ident("WithTimeout"), "(ctx, timeout)")
+	g.P("} else {")
+	g.P("ctx, cancel = ", contextPackage.Ident("WithCancel"), "(ctx)")
+	g.P("}")
+	g.P("return &", stream, "{")
+	g.P("ctx: ctx,")
+	g.P("cancel: cancel,")
+	g.P("spec: ", tripleProtocolPackage.Ident("Spec"), "{StreamType: streamType, Procedure: procedure},")
+	g.P("reqs: make(chan ", protoPackage.Ident("Message"), ", ", inProcessBuffer, "),")
+	g.P("resps: make(chan ", protoPackage.Ident("Message"), ", ", inProcessBuffer, "),")
+	g.P("reqClosed: make(chan struct{}),")
+	g.P("reqHeader: make(", header, "),")
+	g.P("respHeader: make(", header, "),")
+	g.P("respTrailer: make(", header, "),")
+	g.P("done: make(chan struct{}),")
+	g.P("}")
+	g.P("}")
+	g.P()
+	g.P("// start runs handle in its own goroutine and cancels the stream once it returns.")
+	g.P("func (p *", stream, ") start(handle func(", contextPackage.Ident("Context"), ") (", protoPackage.Ident("Message"), ", error)) {")
+	g.P("go func() {")
+	g.P("resp, err := handle(p.ctx)")
+	g.P("if err != nil {")
+	g.P("p.err = ", inProcessHandlerError(s), "(err)")
+	g.P("}")
+	g.P("p.resp = resp")
+	g.P("close(p.done)")
+	g.P("p.cancel()")
+	g.P("}()")
+	g.P("}")
+	g.P()
+	g.P("// end returns the error a receive fails with once the stream stopped: what the")
+	g.P("// handler returned, io.EOF when it succeeded, or the reason of the cancellation.")
+	g.P("func (p *", stream, ") end() error {")
+	g.P("select {")
+	g.P("case <-p.done:")
+	g.P("if p.err != nil {")
+	g.P("return p.err")
+	g.P("}")
+	g.P("return ", ioPackage.Ident("EOF"))
+	g.P("default:")
+	g.P("return ", inProcessError(s), "(p.ctx.Err())")
+	g.P("}")
+	g.P("}")
+	g.P()
+	g.P("func (p *", stream, ") clientSend(msg ", protoPackage.Ident("Message"), ") error {")
+	g.P("select {")
+	g.P("case p.reqs <- ", protoPackage.Ident("Clone"), "(msg):")
+	g.P("return nil")
+	g.P("case <-p.done:")
+	g.P("return ", ioPackage.Ident("EOF"))
+	g.P("case <-p.ctx.Done():")
+	g.P("return p.end()")
+	g.P("}")
+	g.P("}")
+	g.P()
+	g.P("func (p *", stream, ") clientRecv() (", protoPackage.Ident("Message"), ", error) {")
+	g.P("select {")
+	g.P("case msg := <-p.resps:")
+	g.P("return msg, nil")
+	g.P("case <-p.done:")
+	g.P("case <-p.ctx.Done():")
+	g.P("}")
+	g.P("// The handler may have sent messages that are still buffered.")
+	g.P("select {")
+	g.P("case msg := <-p.resps:")
+	g.P("return msg, nil")
+	g.P("default:")
+	g.P("return nil, p.end()")
+	g.P("}")
+	g.P("}")
+	g.P()
+	g.P("func (p *", stream, ") closeRequest() {")
+	g.P("p.closeReq.Do(func() {")
+	g.P("close(p.reqClosed)")
+	g.P("})")
+	g.P("}")
+	g.P()
+	g.P("func (p *", stream, ") closeAndRecv() (", protoPackage.Ident("Message"), ", error) {")
+	g.P("p.closeRequest()")
+	g.P("select {")
+	g.P("case <-p.done:")
+	g.P("if p.err != nil {")
+	g.P("return nil, p.err")
+	g.P("}")
+	g.P("return ", protoPackage.Ident("Clone"), "(p.resp), nil")
+	g.P("case <-p.ctx.Done():")
+	g.P("return nil, p.end()")
+	g.P("}")
+	g.P("}")
+	g.P()
+	g.P("func (p *", stream, ") serverSend(msg ", protoPackage.Ident("Message"), ") error {")
+	g.P("select {")
+	g.P("case p.resps <- ", protoPackage.Ident("Clone"), "(msg):")
+	g.P("return nil")
+	g.P("case <-p.ctx.Done():")
+	g.P("return ", inProcessError(s), "(p.ctx.Err())")
+	g.P("}")
+	g.P("}")
+	g.P()
+	g.P("func (p *", stream, ") serverRecv() (", protoPackage.Ident("Message"), ", error) {")
+	g.P("select {")
+	g.P("case msg := <-p.reqs:")
+	g.P("return msg, nil")
+	g.P("case <-p.reqClosed:")
+	g.P("case <-p.ctx.Done():")
+	g.P("return nil, ", inProcessError(s), "(p.ctx.Err())")
+	g.P("}")
+	g.P("// The client may have sent messages before closing its side.")
+	g.P("select {")
+	g.P("case msg := <-p.reqs:")
+	g.P("return msg, nil")
+	g.P("default:")
+	g.P("return nil, ", ioPackage.Ident("EOF"))
+	g.P("}")
+	g.P("}")
+	g.P()
+	g.P("func (p *", stream, ") clientSpec() ", tripleProtocolPackage.Ident("Spec"), " {")
+	g.P("spec := p.spec")
+	g.P("spec.IsClient = true")
+	g.P("return spec")
+	g.P("}")
+	g.P()
+}
+
+// genInProcessConn writes what Conn returns on the handler side of in-process
+// streams: there is no connection underneath, so sending and receiving through
+// it fail, while the rest reflects the stream.
+func genInProcessConn(g *protogen.GeneratedFile, s Service) {
+	conn := inProcessConn(s)
+	header := httpPackage.Ident("Header")
+	g.P("type ", conn, " struct {")
+	g.P("p *", inProcessStream(s))
+	g.P("}")
+	g.P()
+	g.P("func (c ", conn, ") Spec() ", tripleProtocolPackage.Ident("Spec"), " {")
+	g.P("return c.p.spec")
+	g.P("}")
+	g.P()
+	g.P("func (c ", conn, ") Peer() ", tripleProtocolPackage.Ident("Peer"), " {")
+	g.P("return ", tripleProtocolPackage.Ident("Peer"), "{}")
+	g.P("}")
+	g.P()
+	g.P("func (c ", conn, ") Receive(interface{}) error {")
+	g.P("return c.err()")
+	g.P("}")
+	g.P()
+	g.P("func (c ", conn, ") RequestHeader() ", header, " {")
+	g.P("return c.p.reqHeader")
+	g.P("}")
+	g.P()
+	g.P("func (c ", conn, ") Send(interface{}) error {")
+	g.P("return c.err()")
+	g.P("}")
+	g.P()
+	g.P("func (c ", conn, ") ExportableHeader() ", header, " {")
+	g.P("return c.p.reqHeader")
+	g.P("}")
+	g.P()
+	g.P("func (c ", conn, ") ResponseHeader() ", header, " {")
+	g.P("return c.p.respHeader")
+	g.P("}")
+	g.P()
+	g.P("func (c ", conn, ") ResponseTrailer() ", header, " {")
+	g.P("return c.p.respTrailer")
+	g.P("}")
+	g.P()
+	g.P("func (c ", conn, ") err() error {")
+	g.P("return ", tripleProtocolPackage.Ident("NewError"), "(", tripleProtocolPackage.Ident("CodeUnimplemented"), ", ",
+		errorsPackage.Ident("New"), "(", strconv.Quote("in-process stream has no connection"), "))")
+	g.P("}")
+	g.P()
+}
+
+func genInProcessStreamClient(g *protogen.GeneratedFile, s Service, m Method) {
+	client := inProcessStreamClient(s, m)
+	header := httpPackage.Ident("Header")
+	g.P("type ", client, " struct {")
+	g.P("*", inProcessStream(s))
+	if !m.StreamsRequest {
+		g.P("msg *", m.ReturnType)
+		g.P("recvErr error")
+	}
+	g.P("}")
+	g.P()
+	if m.StreamsRequest {
+		g.P("func (c *", client, ") Spec() ", tripleProtocolPackage.Ident("Spec"), " {")
+		g.P("return c.clientSpec()")
+		g.P("}")
+		g.P()
+		g.P("func (c *", client, ") Peer() ", tripleProtocolPackage.Ident("Peer"), " {")
+		g.P("return ", tripleProtocolPackage.Ident("Peer"), "{}")
+		g.P("}")
+		g.P()
+		g.P("func (c *", client, ") Send(msg *", m.RequestType, ") error {")
+		g.P("return c.clientSend(msg)")
+		g.P("}")
+		g.P()
+		g.P("func (c *", client, ") RequestHeader() ", header, " {")
+		g.P("return c.reqHeader")
+		g.P("}")
+		g.P()
+	}
+	switch {
+	case m.StreamsRequest && m.StreamsReturn:
+		g.P("func (c *", client, ") CloseRequest() error {")
+		g.P("c.closeRequest()")
+		g.P("return nil")
+		g.P("}")
+		g.P()
+		g.P("func (c *", client, ") Recv() (*", m.ReturnType, ", error) {")
+		g.P("msg, err := c.clientRecv()")
+		g.P("if err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return msg.(*", m.ReturnType, "), nil")
+		g.P("}")
+		g.P()
+		genAll(g, "c", client, m.ReturnType, true)
+		g.P("func (c *", client, ") CloseResponse() error {")
+		g.P("c.cancel()")
+		g.P("return nil")
+		g.P("}")
+		g.P()
+	case m.StreamsRequest:
+		g.P("func (c *", client, ") CloseAndRecv() (*", m.ReturnType, ", error) {")
+		g.P("msg, err := c.closeAndRecv()")
+		g.P("if err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return msg.(*", m.ReturnType, "), nil")
+		g.P("}")
+		g.P()
+	default:
+		g.P("func (c *", client, ") Recv() bool {")
+		g.P("msg, err := c.clientRecv()")
+		g.P("if err != nil {")
+		g.P("if !", errorsPackage.Ident("Is"), "(err, ", ioPackage.Ident("EOF"), ") {")
+		g.P("c.recvErr = err")
+		g.P("}")
+		g.P("return false")
+		g.P("}")
+		g.P("c.msg = msg.(*", m.ReturnType, ")")
+		g.P("return true")
+		g.P("}")
+		g.P()
+		g.P("func (c *", client, ") Msg() *", m.ReturnType, " {")
+		g.P("if c.msg == nil {")
+		g.P("return new(", m.ReturnType, ")")
+		g.P("}")
+		g.P("return c.msg")
+		g.P("}")
+		g.P()
+		g.P("func (c *", client, ") Err() error {")
+		g.P("return c.recvErr")
+		g.P("}")
+		g.P()
+		genAll(g, "c", client, m.ReturnType, false)
+		g.P("func (c *", client, ") Close() error {")
+		g.P("c.cancel()")
+		g.P("return nil")
+		g.P("}")
+		g.P()
+	}
+	if m.StreamsReturn {
+		g.P("func (c *", client, ") ResponseHeader() ", header, " {")
+		g.P("return c.respHeader")
+		g.P("}")
+		g.P()
+		g.P("func (c *", client, ") ResponseTrailer() ", header, " {")
+		g.P("return c.respTrailer")
+		g.P("}")
+		g.P()
+	}
+	if !(m.StreamsRequest && m.StreamsReturn) {
+		g.P("func (c *", client, ") Conn() (", tripleProtocolPackage.Ident("StreamingClientConn"), ", error) {")
+		g.P("return nil, ", errorsPackage.Ident("New"), "(", strconv.Quote("in-process stream has no connection"), ")")
+		g.P("}")
+		g.P()
+	}
+}
+
+func genInProcessStreamServer(g *protogen.GeneratedFile, s Service, m Method) {
+	server := inProcessStreamServer(s, m)
+	header := httpPackage.Ident("Header")
+	g.P("type ", server, " struct {")
+	g.P("*", inProcessStream(s))
+	if m.StreamsRequest && !m.StreamsReturn {
+		g.P("msg *", m.RequestType)
+		g.P("recvErr error")
+	}
+	g.P("}")
+	g.P()
+	if m.StreamsRequest {
+		g.P("func (srv *", server, ") Spec() ", tripleProtocolPackage.Ident("Spec"), " {")
+		g.P("return srv.spec")
+		g.P("}")
+		g.P()
+		g.P("func (srv *", server, ") Peer() ", tripleProtocolPackage.Ident("Peer"), " {")
+		g.P("return ", tripleProtocolPackage.Ident("Peer"), "{}")
+		g.P("}")
+		g.P()
+		g.P("func (srv *", server, ") RequestHeader() ", header, " {")
+		g.P("return srv.reqHeader")
+		g.P("}")
+		g.P()
+	}
+	if m.StreamsReturn {
+		g.P("func (srv *", server, ") Send(msg *", m.ReturnType, ") error {")
+		g.P("return srv.serverSend(msg)")
+		g.P("}")
+		g.P()
+		g.P("func (srv *", server, ") ResponseHeader() ", header, " {")
+		g.P("return srv.respHeader")
+		g.P("}")
+		g.P()
+		g.P("func (srv *", server, ") ResponseTrailer() ", header, " {")
+		g.P("return srv.respTrailer")
+		g.P("}")
+		g.P()
+	}
+	switch {
+	case m.StreamsRequest && m.StreamsReturn:
+		g.P("func (srv *", server, ") Recv() (*", m.RequestType, ", error) {")
+		g.P("msg, err := srv.serverRecv()")
+		g.P("if err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return msg.(*", m.RequestType, "), nil")
+		g.P("}")
+		g.P()
+		genAll(g, "srv", server, m.RequestType, true)
+	case m.StreamsRequest:
+		g.P("func (srv *", server, ") Recv() bool {")
+		g.P("msg, err := srv.serverRecv()")
+		g.P("if err != nil {")
+		g.P("if !", errorsPackage.Ident("Is"), "(err, ", ioPackage.Ident("EOF"), ") {")
+		g.P("srv.recvErr = err")
+		g.P("}")
+		g.P("return false")
+		g.P("}")
+		g.P("srv.msg = msg.(*", m.RequestType, ")")
+		g.P("return true")
+		g.P("}")
+		g.P()
+		g.P("func (srv *", server, ") Msg() *", m.RequestType, " {")
+		g.P("if srv.msg == nil {")
+		g.P("return new(", m.RequestType, ")")
+		g.P("}")
+		g.P("return srv.msg")
+		g.P("}")
+		g.P()
+		g.P("func (srv *", server, ") Err() error {")
+		g.P("return srv.recvErr")
+		g.P("}")
+		g.P()
+		genAll(g, "srv", server, m.RequestType, false)
+	}
+	g.P("func (srv *", server, ") Conn() ", tripleProtocolPackage.Ident("StreamingHandlerConn"), " {")
+	g.P("return ", inProcessConn(s), "{srv.", inProcessStream(s), "}")
+	g.P("}")
+	g.P()
+}
+
+func inProcessClient(s Service) string {
+	return s.GoName + "InProcess"
+}
+
+func inProcessError(s Service) string {
+	return unexported(s.GoName + "InProcessError")
+}
+
+func inProcessHandlerError(s Service) string {
+	return unexported(s.GoName + "InProcessHandlerError")
+}
+
+func inProcessStream(s Service) string {
+	return unexported(s.GoName + "InProcessStream")
+}
+
+func inProcessTimeout(s Service) string {
+	return unexported(s.GoName + "InProcessTimeout")
+}
+
+func inProcessConn(s Service) string {
+	return unexported(s.GoName + "InProcessConn")
+}
+
+func inProcessStreamClient(s Service, m Method) string {
+	return unexported(s.GoName + m.GoName + "InProcessClient")
+}
+
+func inProcessStreamServer(s Service, m Method) string {
+	return unexported(s.GoName + m.GoName + "InProcessServer")
+}
+
+// inProcessMembers lists the members of the in-process client, which has a
+// method per RPC next to Close.
+func inProcessMembers(s Service) []typeMembers {
+	if !*InProcess {
+		return nil
+	}
+	t := typeMembers{typeName: inProcessClient(s)}
+	t.members = append(t.members, member{name: "Close"})
+	for i := range s.Methods {
+		t.members = append(t.members, member{name: s.Methods[i].GoName, method: &s.Methods[i]})
+	}
+	return []typeMembers{t}
+}
+
+// inProcessIdents lists the package-level identifiers genInProcess declares for s.
+func inProcessIdents(s Service) []string {
+	if !*InProcess {
+		return nil
+	}
+	idents := []string{"New" + s.GoName + "InProcess", inProcessClient(s), inProcessError(s), inProcessHandlerError(s), inProcessTimeout(s)}
+	if hasStream(s) {
+		idents = append(idents, inProcessStream(s), "new"+s.GoName+"InProcessStream", inProcessConn(s))
+	}
+	for _, m := range s.Methods {
+		if m.isStream() {
+			idents = append(idents, inProcessStreamClient(s, m), inProcessStreamServer(s, m))
+		}
+	}
+	return idents
+}
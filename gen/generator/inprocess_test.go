@@ -0,0 +1,86 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenInProcess(t *testing.T) {
+	setParams(t, "inprocess=true")
+	plugin := newPlugin(t, newRequest(t, `
+		name: "greet.proto"
+		package: "greet"
+		options { go_package: "example.com/greet" }
+		`+greetMessages+greetStreams))
+	stubs := generate(t, plugin, "greet.proto")
+	for _, want := range []string{
+		// Unary calls copy the request and the response.
+		"res, err := c.handler.Greet(ctx, proto.Clone(req).(*GreetRequest))",
+		"return proto.Clone(res).(*GreetResponse), nil",
+		// Streams copy every message passing through them.
+		"case p.reqs <- proto.Clone(msg):",
+		"case p.resps <- proto.Clone(msg):",
+		"return proto.Clone(p.resp), nil",
+		// Call options apply their timeout.
+		"timeout, err := greetServiceInProcessTimeout(opts)",
+		"stream := newGreetServiceInProcessStream(ctx, timeout, GreetServiceGreetBidiProcedure, triple_protocol.StreamTypeBidi)",
+		// Both directions buffer, so a client sends on before receiving.
+		"reqs:        make(chan proto.Message, 16),",
+		"resps:       make(chan proto.Message, 16),",
+		// Handler errors reach the caller as triple errors.
+		"return nil, greetServiceInProcessHandlerError(err)",
+		"p.err = greetServiceInProcessHandlerError(err)",
+		"return triple_protocol.NewError(triple_protocol.CodeUnknown, err)",
+		// Close cancels the open streams and fails later calls.
+		"func (c *GreetServiceInProcess) Close() error {",
+		"if err := c.track(stream); err != nil {",
+		"if err := c.open(); err != nil {",
+		// Conn works on the handler side.
+		"return greetServiceInProcessConn{srv.greetServiceInProcessStream}",
+		"func (c greetServiceInProcessConn) ExportableHeader() http.Header {",
+	} {
+		if !strings.Contains(stubs, want) {
+			t.Errorf("generated in-process client lacks %q", want)
+		}
+	}
+	if strings.Contains(stubs, "StreamingHandlerConn {\n\treturn nil\n}") {
+		t.Error("Conn of an in-process stream returns nil")
+	}
+	if strings.Contains(stubs, "SetFinalizer") {
+		t.Error("in-process streams rely on a finalizer")
+	}
+}
+
+func TestInProcessCloseCollision(t *testing.T) {
+	setParams(t, "inprocess=true")
+	plugin := newPlugin(t, newRequest(t, `
+		name: "greet.proto"
+		package: "greet"
+		options { go_package: "example.com/greet" }
+		`+greetMessages+`
+		service {
+			name: "GreetService"
+			`+rpc("Close")+`
+		}`))
+	err := CheckCollisions(plugin)
+	if err == nil || !strings.Contains(err.Error(), `member "Close" of GreetServiceInProcess`) {
+		t.Errorf("CheckCollisions() = %v, want the Close collision", err)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"path"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/dubbogo/protoc-gen-go-triple/v3/dubbo"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Values of the interface_name option.
+const (
+	// InterfaceNameProto registers services under their fully-qualified proto name.
+	InterfaceNameProto = "proto"
+	// InterfaceNameJava registers services under the name of the Java interface
+	// protoc generates for them, so Go and Java providers share a registry key.
+	InterfaceNameJava = "java"
+)
+
+// interfaceName returns the name service is registered and referenced under in
+// dubbo: the interface_name of its dubbo.service option, or else the name the
+// interface_name plugin parameter selects.
+func interfaceName(file *protogen.File, service *protogen.Service) string {
+	ext, _ := proto.GetExtension(service.Desc.Options(), dubbo.E_Service).(*dubbo.ServiceOptions)
+	if name := ext.GetInterfaceName(); name != "" {
+		return name
+	}
+	if *InterfaceName == InterfaceNameJava {
+		return javaInterfaceName(file, service)
+	}
+	return string(service.Desc.FullName())
+}
+
+// javaInterfaceName returns the binary name of the Java interface of service:
+// it is declared in java_package, or the proto package without one, and nested
+// in the outer class of the file unless java_multiple_files is set.
+func javaInterfaceName(file *protogen.File, service *protogen.Service) string {
+	opts := file.Desc.Options().(*descriptorpb.FileOptions)
+	pkg := opts.GetJavaPackage()
+	if pkg == "" {
+		pkg = string(file.Desc.Package())
+	}
+	name := string(service.Desc.Name())
+	if !opts.GetJavaMultipleFiles() {
+		name = javaOuterClassname(file) + "$" + name
+	}
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
+
+// javaOuterClassname returns java_outer_classname, or the name protoc derives
+// from the file name: CamelCased, with "OuterClass" appended when it clashes
+// with the name of a message, enum or service of the file.
+func javaOuterClassname(file *protogen.File) string {
+	opts := file.Desc.Options().(*descriptorpb.FileOptions)
+	if name := opts.GetJavaOuterClassname(); name != "" {
+		return name
+	}
+	base := strings.TrimSuffix(path.Base(file.Desc.Path()), ".proto")
+	var b strings.Builder
+	upper := true
+	for _, c := range base {
+		switch {
+		case c >= 'a' && c <= 'z':
+			if upper {
+				c -= 'a' - 'A'
+			}
+			b.WriteRune(c)
+			upper = false
+		case c >= 'A' && c <= 'Z':
+			b.WriteRune(c)
+			upper = false
+		case c >= '0' && c <= '9':
+			b.WriteRune(c)
+			upper = true
+		default:
+			upper = true
+		}
+	}
+	name := b.String()
+	for _, s := range file.Services {
+		if string(s.Desc.Name()) == name {
+			return name + "OuterClass"
+		}
+	}
+	if javaNameClashes(name, file.Messages, file.Enums) {
+		return name + "OuterClass"
+	}
+	return name
+}
+
+func javaNameClashes(name string, messages []*protogen.Message, enums []*protogen.Enum) bool {
+	for _, e := range enums {
+		if string(e.Desc.Name()) == name {
+			return true
+		}
+	}
+	for _, m := range messages {
+		if string(m.Desc.Name()) == name || javaNameClashes(name, m.Messages, m.Enums) {
+			return true
+		}
+	}
+	return false
+}
+
+// interfaceNameExpr returns the expression generated code uses for the interface
+// name of s: the {Service}InterfaceName constant when it differs from the
+// fully-qualified service name, or else the name itself.
+func interfaceNameExpr(s Service) string {
+	if s.InterfaceName != s.FullName {
+		return s.GoName + "InterfaceName"
+	}
+	return strconv.Quote(s.FullName)
+}
@@ -0,0 +1,171 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInterfaceName(t *testing.T) {
+	tests := []struct {
+		name  string
+		param string
+		file  string
+		// options are the file options besides go_package.
+		options string
+		// service is the name of the service to look up, GreetService by default.
+		service string
+		want    string
+	}{
+		{
+			name:  "proto name",
+			param: "interface_name=proto",
+			file:  "greet.proto",
+			want:  "greet.GreetService",
+		},
+		{
+			name: "java_multiple_files",
+			file: "greet.proto", options: `java_package: "com.acme.greet" java_multiple_files: true`,
+			want: "com.acme.greet.GreetService",
+		},
+		{
+			name: "nested in the outer class",
+			file: "greet.proto", options: `java_package: "com.acme.greet"`,
+			want: "com.acme.greet.Greet$GreetService",
+		},
+		{
+			name: "java_outer_classname",
+			file: "greet.proto", options: `java_package: "com.acme.greet" java_outer_classname: "GreetProto"`,
+			want: "com.acme.greet.GreetProto$GreetService",
+		},
+		{
+			name: "proto package without java_package",
+			file: "greet.proto",
+			want: "greet.Greet$GreetService",
+		},
+		{
+			name: "digits and dashes in the file name",
+			file: "dir/greet_v2-beta3x.proto",
+			want: "greet.GreetV2Beta3X$GreetService",
+		},
+		{
+			name: "outer class clashing with the service",
+			file: "greet_service.proto",
+			want: "greet.GreetServiceOuterClass$GreetService",
+		},
+		{
+			name:    "outer class clashing with a message",
+			file:    "greet_request.proto",
+			service: "GreetService",
+			want:    "greet.GreetRequestOuterClass$GreetService",
+		},
+		{
+			name: "outer class clashing with a nested message",
+			file: "nested.proto",
+			want: "greet.NestedOuterClass$GreetService",
+		},
+		{
+			name:    "override",
+			file:    "greet.proto",
+			options: `java_package: "com.acme.greet" java_multiple_files: true`,
+			service: "OverriddenService",
+			want:    "com.acme.Overridden",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			param := tt.param
+			if param == "" {
+				param = "interface_name=java"
+			}
+			setParams(t, param)
+			plugin := newPlugin(t, newRequest(t, `
+				name: "`+tt.file+`"
+				package: "greet"
+				options { go_package: "example.com/greet" `+tt.options+` }
+				`+greetMessages+`
+				message_type { name: "Outer" nested_type { name: "Nested" } }
+				service { name: "GreetService" `+rpc("Greet")+` }
+				service {
+					name: "OverriddenService" `+rpc("Greet")+`
+					options { [dubbo.service] { interface_name: "com.acme.Overridden" } }
+				}`))
+			file := plugin.FilesByPath[tt.file]
+			service := tt.service
+			if service == "" {
+				service = "GreetService"
+			}
+			for _, s := range file.Services {
+				if string(s.Desc.Name()) == service {
+					if got := interfaceName(file, s); got != tt.want {
+						t.Errorf("interface name %q, want %q", got, tt.want)
+					}
+					return
+				}
+			}
+			t.Fatalf("no service %s", service)
+		})
+	}
+}
+
+func TestGenInterfaceName(t *testing.T) {
+	setParams(t, "interface_name=java", "openapi=true")
+	plugin := newPlugin(t, newRequest(t, `
+		name: "greet.proto"
+		package: "greet"
+		options { go_package: "example.com/greet" java_package: "com.acme.greet" java_multiple_files: true }
+		`+greetMessages+`
+		service {
+			name: "GreetService"
+			`+rpc("Greet")+`
+		}`))
+	stubs := generate(t, plugin, "greet.proto")
+	// The interface name goes into the metadata, while the wire path keeps the
+	// proto name.
+	for _, want := range []string{
+		`GreetServiceInterfaceName = "com.acme.greet.GreetService"`,
+		`GreetServiceGreetProcedure = "/greet.GreetService/Greet"`,
+		`InterfaceName: GreetServiceInterfaceName,`,
+		`conn, err := cli.DialWithInfo(GreetServiceInterfaceName, &GreetService_ClientInfo, opts...)`,
+	} {
+		if !strings.Contains(stubs, want) {
+			t.Errorf("generated stubs lack %q", want)
+		}
+	}
+	if strings.Contains(stubs, `"/com.acme.greet.GreetService/`) {
+		t.Error("generated stubs use the interface name in a path")
+	}
+
+	file := plugin.FilesByPath["greet.proto"]
+	tripleGo, err := ProcessProtoFile(plugin, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := plugin.NewGeneratedFile(OpenAPIFilename(file), "")
+	if err := GenOpenAPIFile(g, "greet", []TripleGo{tripleGo}); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := g.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(doc), `"/greet.GreetService/Greet":`) {
+		t.Errorf("OpenAPI document lacks the served path:\n%s", doc)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// Values of the iterators option besides "false".
+const (
+	// IteratorsSeq declares All as returning an iter.Seq2, which needs Go 1.23.
+	IteratorsSeq = "true"
+	// IteratorsFunc declares All as returning the function type underlying
+	// iter.Seq2. It compiles with any Go version, ranges like an iter.Seq2 from
+	// Go 1.23 on and can be called with a yield function before.
+	IteratorsFunc = "func"
+)
+
+const iterPackage = protogen.GoImportPath("iter")
+
+// iterators reports whether the receiving stream interfaces get an All method.
+func iterators() bool {
+	return *Iterators == IteratorsSeq || *Iterators == IteratorsFunc
+}
+
+// seqType returns the arguments of g.P printing the result type of All for
+// streams receiving msg.
+func seqType(msg protogen.GoIdent) []interface{} {
+	if *Iterators == IteratorsFunc {
+		return []interface{}{"func(yield func(*", msg, ", error) bool)"}
+	}
+	return []interface{}{iterPackage.Ident("Seq2"), "[*", msg, ", error]"}
+}
+
+// genAllDecl writes the All method into a stream interface receiving msg.
+func genAllDecl(g *protogen.GeneratedFile, msg protogen.GoIdent) {
+	if !iterators() {
+		return
+	}
+	g.P(append([]interface{}{"All() "}, seqType(msg)...)...)
+}
+
+// genAll writes the All method of a stream implementation receiving msg with
+// recv. Streams whose Recv returns the message and an error end with io.EOF,
+// and the others with Recv returning false and Err the error, if any.
+func genAll(g *protogen.GeneratedFile, recv, impl string, msg protogen.GoIdent, recvMsg bool) {
+	if !iterators() {
+		return
+	}
+	g.P("// All returns an iterator over the received messages. It stops at the end of")
+	g.P("// the stream, or yields the error ending it as its last element.")
+	g.P(append(append([]interface{}{"func (", recv, " *", impl, ") All() "}, seqType(msg)...), " {")...)
+	g.P("return func(yield func(*", msg, ", error) bool) {")
+	if recvMsg {
+		g.P("for {")
+		g.P("msg, err := ", recv, ".Recv()")
+		g.P("if ", errorsPackage.Ident("Is"), "(err, ", ioPackage.Ident("EOF"), ") {")
+		g.P("return")
+		g.P("}")
+		g.P("if err != nil {")
+		g.P("yield(nil, err)")
+		g.P("return")
+		g.P("}")
+		g.P("if !yield(msg, nil) {")
+		g.P("return")
+		g.P("}")
+		g.P("}")
+	} else {
+		g.P("for ", recv, ".Recv() {")
+		g.P("if !yield(", recv, ".Msg(), nil) {")
+		g.P("return")
+		g.P("}")
+		g.P("}")
+		g.P("if err := ", recv, ".Err(); err != nil {")
+		g.P("yield(nil, err)")
+		g.P("}")
+	}
+	g.P("}")
+	g.P("}")
+	g.P()
+}
@@ -0,0 +1,125 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+// interfaceDecl returns the declaration of the interface named name in stubs.
+func interfaceDecl(t *testing.T, stubs, name string) string {
+	t.Helper()
+	start := strings.Index(stubs, "type "+name+" interface {")
+	if start < 0 {
+		t.Fatalf("no interface %s", name)
+	}
+	end := strings.Index(stubs[start:], "\n}\n")
+	return stubs[start : start+end]
+}
+
+func TestGenIterators(t *testing.T) {
+	tests := []struct {
+		iterators string
+		// response and request are the result types of All for streams
+		// receiving responses and requests, empty when there is no All.
+		response, request string
+	}{
+		{iterators: "false"},
+		{
+			iterators: "true",
+			response:  "iter.Seq2[*GreetResponse, error]",
+			request:   "iter.Seq2[*GreetRequest, error]",
+		},
+		{
+			iterators: "func",
+			response:  "func(yield func(*GreetResponse, error) bool)",
+			request:   "func(yield func(*GreetRequest, error) bool)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.iterators, func(t *testing.T) {
+			setParams(t, "iterators="+tt.iterators, "inprocess=true")
+			if err := CheckOptions(); err != nil {
+				t.Fatal(err)
+			}
+			plugin := newPlugin(t, newRequest(t, `
+				name: "greet.proto"
+				package: "greet"
+				options { go_package: "example.com/greet" }
+				`+greetMessages+greetStreams))
+			stubs := generate(t, plugin, "greet.proto")
+
+			// Only iter.Seq2 needs the iter package, and Go 1.23.
+			if got := strings.Contains(stubs, `iter "iter"`); got != (tt.iterators == IteratorsSeq) {
+				t.Errorf("stubs import iter: %v", got)
+			}
+			if tt.response == "" {
+				if strings.Contains(stubs, "All()") {
+					t.Error("stubs declare All without iterators")
+				}
+				return
+			}
+
+			// Streams receiving more than one message get All, the others do not.
+			for name, want := range map[string]string{
+				"GreetService_GreetServerClient": tt.response,
+				"GreetService_GreetBidiClient":   tt.response,
+				"GreetService_GreetClientServer": tt.request,
+				"GreetService_GreetBidiServer":   tt.request,
+				"GreetService_GreetClientClient": "",
+				"GreetService_GreetServerServer": "",
+			} {
+				decl := interfaceDecl(t, stubs, name)
+				if want == "" {
+					if strings.Contains(decl, "All()") {
+						t.Errorf("%s declares All", name)
+					}
+				} else if !strings.Contains(decl, "\tAll() "+want+"\n") {
+					t.Errorf("%s lacks All() %s:\n%s", name, want, decl)
+				}
+			}
+			// The wire and in-process implementations.
+			for _, want := range []string{
+				"func (cli *GreetServiceGreetServerClient) All() " + tt.response + " {",
+				"func (cli *GreetServiceGreetBidiClient) All() " + tt.response + " {",
+				"func (srv *GreetServiceGreetClientServer) All() " + tt.request + " {",
+				"func (srv *GreetServiceGreetBidiServer) All() " + tt.request + " {",
+				"func (c *greetServiceGreetServerInProcessClient) All() " + tt.response + " {",
+				"func (c *greetServiceGreetBidiInProcessClient) All() " + tt.response + " {",
+				"func (srv *greetServiceGreetClientInProcessServer) All() " + tt.request + " {",
+				"func (srv *greetServiceGreetBidiInProcessServer) All() " + tt.request + " {",
+				// Both forms return the same function literal.
+				"\treturn func(yield func(*GreetResponse, error) bool) {\n",
+				"\treturn func(yield func(*GreetRequest, error) bool) {\n",
+			} {
+				if !strings.Contains(stubs, want) {
+					t.Errorf("stubs lack %q", want)
+				}
+			}
+			if n := strings.Count(stubs, ") All() "); n != 8 {
+				t.Errorf("stubs implement All %d times, want 8", n)
+			}
+		})
+	}
+
+	setParams(t, "iterators=seq")
+	if err := CheckOptions(); err == nil {
+		t.Error("CheckOptions accepted iterators=seq")
+	}
+}
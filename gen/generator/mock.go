@@ -0,0 +1,615 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"strconv"
+	"strings"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+const ioPackage = protogen.GoImportPath("io")
+
+// testingT is the part of testing.TB the expectation helpers use. It is spelled
+// out so the mocks do not make the stubs package import testing.
+const testingT = "interface {\nHelper()\nErrorf(format string, args ...interface{})\n}"
+
+// MockFilename returns the name of the mock file generated next to filename.
+func MockFilename(filename string) string {
+	return strings.TrimSuffix(filename, ".go") + "_mock.go"
+}
+
+// GenMockFile writes programmable mocks of the client and handler interfaces in
+// triple, and fake streams replaying scripted messages, into genFile.
+func GenMockFile(genFile *protogen.GeneratedFile, triple TripleGo) error {
+	genPreamble(genFile, triple)
+	for _, s := range triple.Services {
+		genMockCalls(genFile, s)
+		if *Client {
+			genClientMock(genFile, s)
+			genFakeClientStreams(genFile, s)
+		}
+		if *Server {
+			genHandlerMock(genFile, s)
+			genFakeServerStreams(genFile, s)
+		}
+	}
+	return nil
+}
+
+// genMockCalls writes the types recording a single call of every method.
+func genMockCalls(g *protogen.GeneratedFile, s Service) {
+	for _, m := range s.Methods {
+		g.P("// ", mockCall(s, m), " records a call of ", m.GoName, ".")
+		g.P("type ", mockCall(s, m), " struct {")
+		g.P("Ctx ", contextPackage.Ident("Context"))
+		if !m.StreamsRequest {
+			g.P("Req *", m.RequestType)
+		}
+		g.P("}")
+		g.P()
+	}
+}
+
+func genClientMock(g *protogen.GeneratedFile, s Service) {
+	mock := "Mock" + s.GoName
+	g.P("// ", mock, " is a programmable ", s.GoName, " for tests. Every method records its call")
+	g.P("// and delegates to the matching Func field; methods whose Func is nil fail with")
+	g.P("// CodeUnimplemented.")
+	g.P("type ", mock, " struct {")
+	for _, m := range s.Methods {
+		g.P(m.GoName, "Func func", clientSignature(g, s, m))
+	}
+	g.P()
+	g.P("mu ", syncPackage.Ident("Mutex"))
+	for _, m := range s.Methods {
+		g.P(mockCallsField(m), " []", mockCall(s, m))
+	}
+	g.P("}")
+	g.P()
+	g.P("var _ ", s.GoName, " = (*", mock, ")(nil)")
+	g.P()
+	for _, m := range s.Methods {
+		args := "ctx"
+		call := mockCall(s, m) + "{Ctx: ctx}"
+		if !m.StreamsRequest {
+			args += ", req"
+			call = mockCall(s, m) + "{Ctx: ctx, Req: req}"
+		}
+		args += ", opts..."
+		g.P("func (m *", mock, ") ", m.GoName, clientSignature(g, s, m), " {")
+		genMockDispatch(g, mock, m, call, args, false)
+		g.P("}")
+		g.P()
+		genMockExpectations(g, mock, s, m)
+	}
+}
+
+func genHandlerMock(g *protogen.GeneratedFile, s Service) {
+	mock := "Mock" + s.GoName + "Handler"
+	g.P("// ", mock, " is a programmable ", s.GoName, "Handler for tests. Every method records")
+	g.P("// its call and delegates to the matching Func field; methods whose Func is nil fail")
+	g.P("// with CodeUnimplemented.")
+	g.P("type ", mock, " struct {")
+	if *RequireUnimplemented {
+		g.P("Unimplemented", s.GoName, "Handler")
+		g.P()
+	}
+	for _, m := range s.Methods {
+		g.P(m.GoName, "Func func", handlerSignature(g, s, m))
+	}
+	g.P()
+	g.P("mu ", syncPackage.Ident("Mutex"))
+	for _, m := range s.Methods {
+		g.P(mockCallsField(m), " []", mockCall(s, m))
+	}
+	g.P("}")
+	g.P()
+	g.P("var _ ", s.GoName, "Handler = (*", mock, ")(nil)")
+	g.P()
+	for _, m := range s.Methods {
+		params := "(ctx " + g.QualifiedGoIdent(contextPackage.Ident("Context"))
+		args := "ctx"
+		call := mockCall(s, m) + "{Ctx: ctx}"
+		if !m.StreamsRequest {
+			params += ", req *" + g.QualifiedGoIdent(m.RequestType)
+			args += ", req"
+			call = mockCall(s, m) + "{Ctx: ctx, Req: req}"
+		}
+		if m.isStream() {
+			params += ", stream " + s.GoName + "_" + m.GoName + "Server"
+			args += ", stream"
+		}
+		results := " (*" + g.QualifiedGoIdent(m.ReturnType) + ", error)"
+		if m.StreamsReturn {
+			results = " error"
+		}
+		g.P("func (m *", mock, ") ", m.GoName, params, ")", results, " {")
+		genMockDispatch(g, mock, m, call, args, m.StreamsReturn)
+		g.P("}")
+		g.P()
+		genMockExpectations(g, mock, s, m)
+	}
+}
+
+// genMockDispatch writes the body of a mock method: record the call, then hand
+// it to the stub function.
+func genMockDispatch(g *protogen.GeneratedFile, mock string, m Method, call, args string, errOnly bool) {
+	g.P("m.mu.Lock()")
+	g.P("m.", mockCallsField(m), " = append(m.", mockCallsField(m), ", ", call, ")")
+	g.P("stub := m.", m.GoName, "Func")
+	g.P("m.mu.Unlock()")
+	g.P("if stub == nil {")
+	err := g.QualifiedGoIdent(tripleProtocolPackage.Ident("NewError")) + "(" +
+		g.QualifiedGoIdent(tripleProtocolPackage.Ident("CodeUnimplemented")) + ", " +
+		g.QualifiedGoIdent(errorsPackage.Ident("New")) + "(" + strconv.Quote(mock+"."+m.GoName+" is not stubbed") + "))"
+	if errOnly {
+		g.P("return ", err)
+	} else {
+		g.P("return nil, ", err)
+	}
+	g.P("}")
+	g.P("return stub(", args, ")")
+}
+
+// genMockExpectations writes the accessors for the calls recorded for m.
+func genMockExpectations(g *protogen.GeneratedFile, mock string, s Service, m Method) {
+	g.P("// ", m.GoName, "Calls returns the calls of ", m.GoName, " so far.")
+	g.P("func (m *", mock, ") ", m.GoName, "Calls() []", mockCall(s, m), " {")
+	g.P("m.mu.Lock()")
+	g.P("defer m.mu.Unlock()")
+	g.P("return append([]", mockCall(s, m), "(nil), m.", mockCallsField(m), "...)")
+	g.P("}")
+	g.P()
+	g.P("// Expect", m.GoName, "Calls reports an error through t unless ", m.GoName, " was called exactly")
+	g.P("// want times. t is usually a *testing.T.")
+	g.P("func (m *", mock, ") Expect", m.GoName, "Calls(t ", testingT, ", want int) {")
+	g.P("t.Helper()")
+	g.P("if got := len(m.", m.GoName, "Calls()); got != want {")
+	g.P("t.Errorf(", strconv.Quote(mock+"."+m.GoName+" called %d times, want %d"), ", got, want)")
+	g.P("}")
+	g.P("}")
+	g.P()
+}
+
+// genFakeClientStreams writes a fake of every client-side stream interface of s.
+func genFakeClientStreams(g *protogen.GeneratedFile, s Service) {
+	for _, m := range s.Methods {
+		if !m.isStream() {
+			continue
+		}
+		fake := "Fake" + s.GoName + m.GoName + "Client"
+		iface := s.GoName + "_" + m.GoName + "Client"
+		switch {
+		case m.StreamsRequest && m.StreamsReturn:
+			g.P("// ", fake, " is a scripted ", iface, ". Send records the requests in Sent,")
+			g.P("// Recv replays Responses and then fails with RecvErr, or io.EOF when RecvErr is nil.")
+			g.P("type ", fake, " struct {")
+			g.P("Sent []*", m.RequestType)
+			g.P("Responses []*", m.ReturnType)
+			g.P("RecvErr error")
+			g.P("SendErr error")
+			g.P("ReqHeader, RespHeader, RespTrailer ", httpPackage.Ident("Header"))
+			g.P("RequestClosed, ResponseClosed bool")
+			g.P()
+			g.P("mu ", syncPackage.Ident("Mutex"))
+			g.P("}")
+			g.P()
+			g.P("var _ ", iface, " = (*", fake, ")(nil)")
+			g.P()
+			genFakeSpec(g, fake, s, m, true)
+			genFakeSend(g, fake, m.RequestType)
+			genFakeHeader(g, fake, "RequestHeader", "ReqHeader")
+			g.P("func (f *", fake, ") CloseRequest() error {")
+			g.P("f.mu.Lock()")
+			g.P("defer f.mu.Unlock()")
+			g.P("f.RequestClosed = true")
+			g.P("return nil")
+			g.P("}")
+			g.P()
+			genFakeRecvMsg(g, fake, "Responses", m.ReturnType)
+			genFakeHeader(g, fake, "ResponseHeader", "RespHeader")
+			genFakeHeader(g, fake, "ResponseTrailer", "RespTrailer")
+			g.P("func (f *", fake, ") CloseResponse() error {")
+			g.P("f.mu.Lock()")
+			g.P("defer f.mu.Unlock()")
+			g.P("f.ResponseClosed = true")
+			g.P("return nil")
+			g.P("}")
+			g.P()
+		case m.StreamsRequest:
+			g.P("// ", fake, " is a scripted ", iface, ". Send records the requests in Sent,")
+			g.P("// CloseAndRecv returns Response, or RecvErr when it is set. It fails with")
+			g.P("// CodeUnimplemented when neither is.")
+			g.P("type ", fake, " struct {")
+			g.P("Sent []*", m.RequestType)
+			g.P("Response *", m.ReturnType)
+			g.P("RecvErr error")
+			g.P("SendErr error")
+			g.P("ReqHeader ", httpPackage.Ident("Header"))
+			g.P("RequestClosed bool")
+			g.P()
+			g.P("mu ", syncPackage.Ident("Mutex"))
+			g.P("}")
+			g.P()
+			g.P("var _ ", iface, " = (*", fake, ")(nil)")
+			g.P()
+			genFakeSpec(g, fake, s, m, true)
+			genFakeSend(g, fake, m.RequestType)
+			genFakeHeader(g, fake, "RequestHeader", "ReqHeader")
+			g.P("func (f *", fake, ") CloseAndRecv() (*", m.ReturnType, ", error) {")
+			g.P("f.mu.Lock()")
+			g.P("defer f.mu.Unlock()")
+			g.P("f.RequestClosed = true")
+			g.P("if f.RecvErr != nil {")
+			g.P("return nil, f.RecvErr")
+			g.P("}")
+			g.P("if f.Response == nil {")
+			g.P("return nil, ", tripleProtocolPackage.Ident("NewError"), "(", tripleProtocolPackage.Ident("CodeUnimplemented"), ", ",
+				errorsPackage.Ident("New"), "(", strconv.Quote(fake+".Response is not scripted"), "))")
+			g.P("}")
+			g.P("return f.Response, nil")
+			g.P("}")
+			g.P()
+			genFakeClientConn(g, fake)
+		default:
+			g.P("// ", fake, " is a scripted ", iface, ". Recv replays Responses, after which")
+			g.P("// Err returns RecvErr.")
+			g.P("type ", fake, " struct {")
+			g.P("Responses []*", m.ReturnType)
+			g.P("RecvErr error")
+			g.P("RespHeader, RespTrailer ", httpPackage.Ident("Header"))
+			g.P("Closed bool")
+			g.P()
+			g.P("mu ", syncPackage.Ident("Mutex"))
+			g.P("next int")
+			g.P("msg *", m.ReturnType)
+			g.P("}")
+			g.P()
+			g.P("var _ ", iface, " = (*", fake, ")(nil)")
+			g.P()
+			genFakeRecvBool(g, fake, "Responses", m.ReturnType)
+			genFakeHeader(g, fake, "ResponseHeader", "RespHeader")
+			genFakeHeader(g, fake, "ResponseTrailer", "RespTrailer")
+			genFakeClientConn(g, fake)
+			g.P("func (f *", fake, ") Close() error {")
+			g.P("f.mu.Lock()")
+			g.P("defer f.mu.Unlock()")
+			g.P("f.Closed = true")
+			g.P("return nil")
+			g.P("}")
+			g.P()
+		}
+	}
+}
+
+// genFakeServerStreams writes a fake of every server-side stream interface of s.
+func genFakeServerStreams(g *protogen.GeneratedFile, s Service) {
+	if hasStream(s) {
+		genFakeHandlerConnType(g, s)
+	}
+	for _, m := range s.Methods {
+		if !m.isStream() {
+			continue
+		}
+		fake := "Fake" + s.GoName + m.GoName + "Server"
+		iface := s.GoName + "_" + m.GoName + "Server"
+		switch {
+		case m.StreamsRequest && m.StreamsReturn:
+			g.P("// ", fake, " is a scripted ", iface, ". Recv replays Requests and then fails")
+			g.P("// with RecvErr, or io.EOF when RecvErr is nil. Send records the responses in Sent.")
+			g.P("type ", fake, " struct {")
+			g.P("Requests []*", m.RequestType)
+			g.P("Sent []*", m.ReturnType)
+			g.P("RecvErr error")
+			g.P("SendErr error")
+			g.P("ReqHeader, RespHeader, RespTrailer ", httpPackage.Ident("Header"))
+			g.P()
+			g.P("mu ", syncPackage.Ident("Mutex"))
+			g.P("}")
+			g.P()
+			g.P("var _ ", iface, " = (*", fake, ")(nil)")
+			g.P()
+			genFakeSend(g, fake, m.ReturnType)
+			genFakeRecvMsg(g, fake, "Requests", m.RequestType)
+			genFakeSpec(g, fake, s, m, false)
+			genFakeHeader(g, fake, "RequestHeader", "ReqHeader")
+			genFakeHeader(g, fake, "ResponseHeader", "RespHeader")
+			genFakeHeader(g, fake, "ResponseTrailer", "RespTrailer")
+			genFakeHandlerConn(g, s, m, fake)
+		case m.StreamsRequest:
+			g.P("// ", fake, " is a scripted ", iface, ". Recv replays Requests, after which")
+			g.P("// Err returns RecvErr.")
+			g.P("type ", fake, " struct {")
+			g.P("Requests []*", m.RequestType)
+			g.P("RecvErr error")
+			g.P("ReqHeader, RespHeader, RespTrailer ", httpPackage.Ident("Header"))
+			g.P()
+			g.P("mu ", syncPackage.Ident("Mutex"))
+			g.P("next int")
+			g.P("msg *", m.RequestType)
+			g.P("}")
+			g.P()
+			g.P("var _ ", iface, " = (*", fake, ")(nil)")
+			g.P()
+			genFakeSpec(g, fake, s, m, false)
+			genFakeRecvBool(g, fake, "Requests", m.RequestType)
+			genFakeHeader(g, fake, "RequestHeader", "ReqHeader")
+			genFakeHandlerConn(g, s, m, fake)
+		default:
+			g.P("// ", fake, " is a scripted ", iface, ". Send records the responses in Sent.")
+			g.P("type ", fake, " struct {")
+			g.P("Sent []*", m.ReturnType)
+			g.P("SendErr error")
+			g.P("ReqHeader, RespHeader, RespTrailer ", httpPackage.Ident("Header"))
+			g.P()
+			g.P("mu ", syncPackage.Ident("Mutex"))
+			g.P("}")
+			g.P()
+			g.P("var _ ", iface, " = (*", fake, ")(nil)")
+			g.P()
+			genFakeSend(g, fake, m.ReturnType)
+			genFakeHeader(g, fake, "ResponseHeader", "RespHeader")
+			genFakeHeader(g, fake, "ResponseTrailer", "RespTrailer")
+			genFakeHandlerConn(g, s, m, fake)
+		}
+	}
+}
+
+func genFakeSpec(g *protogen.GeneratedFile, fake string, s Service, m Method, isClient bool) {
+	streamType := "StreamTypeBidi"
+	switch {
+	case !m.StreamsReturn:
+		streamType = "StreamTypeClient"
+	case !m.StreamsRequest:
+		streamType = "StreamTypeServer"
+	}
+	g.P("func (f *", fake, ") Spec() ", tripleProtocolPackage.Ident("Spec"), " {")
+	g.P("return ", tripleProtocolPackage.Ident("Spec"), "{")
+	g.P("StreamType: ", tripleProtocolPackage.Ident(streamType), ",")
+	g.P("Procedure: ", s.GoName, m.GoName, "Procedure,")
+	g.P("IsClient: ", isClient, ",")
+	g.P("}")
+	g.P("}")
+	g.P()
+	g.P("func (f *", fake, ") Peer() ", tripleProtocolPackage.Ident("Peer"), " {")
+	g.P("return ", tripleProtocolPackage.Ident("Peer"), "{}")
+	g.P("}")
+	g.P()
+}
+
+func genFakeSend(g *protogen.GeneratedFile, fake string, msg protogen.GoIdent) {
+	g.P("func (f *", fake, ") Send(msg *", msg, ") error {")
+	g.P("f.mu.Lock()")
+	g.P("defer f.mu.Unlock()")
+	g.P("if f.SendErr != nil {")
+	g.P("return f.SendErr")
+	g.P("}")
+	g.P("f.Sent = append(f.Sent, msg)")
+	g.P("return nil")
+	g.P("}")
+	g.P()
+}
+
+// genFakeRecvMsg writes the Recv of bidi streams, which returns the next
+// scripted message or the final error, and their All.
+func genFakeRecvMsg(g *protogen.GeneratedFile, fake, field string, msg protogen.GoIdent) {
+	g.P("func (f *", fake, ") Recv() (*", msg, ", error) {")
+	g.P("f.mu.Lock()")
+	g.P("defer f.mu.Unlock()")
+	g.P("if len(f.", field, ") == 0 {")
+	g.P("if f.RecvErr != nil {")
+	g.P("return nil, f.RecvErr")
+	g.P("}")
+	g.P("return nil, ", ioPackage.Ident("EOF"))
+	g.P("}")
+	g.P("msg := f.", field, "[0]")
+	g.P("f.", field, " = f.", field, "[1:]")
+	g.P("return msg, nil")
+	g.P("}")
+	g.P()
+	genAll(g, "f", fake, msg, true)
+}
+
+// genFakeRecvBool writes the Recv, Msg and Err trio of client and server
+// streams that receive more than one message, and their All.
+func genFakeRecvBool(g *protogen.GeneratedFile, fake, field string, msg protogen.GoIdent) {
+	g.P("func (f *", fake, ") Recv() bool {")
+	g.P("f.mu.Lock()")
+	g.P("defer f.mu.Unlock()")
+	g.P("if f.next >= len(f.", field, ") {")
+	g.P("return false")
+	g.P("}")
+	g.P("f.msg = f.", field, "[f.next]")
+	g.P("f.next++")
+	g.P("return true")
+	g.P("}")
+	g.P()
+	g.P("func (f *", fake, ") Msg() *", msg, " {")
+	g.P("f.mu.Lock()")
+	g.P("defer f.mu.Unlock()")
+	g.P("if f.msg == nil {")
+	g.P("return new(", msg, ")")
+	g.P("}")
+	g.P("return f.msg")
+	g.P("}")
+	g.P()
+	g.P("func (f *", fake, ") Err() error {")
+	g.P("f.mu.Lock()")
+	g.P("defer f.mu.Unlock()")
+	g.P("if f.next < len(f.", field, ") {")
+	g.P("return nil")
+	g.P("}")
+	g.P("return f.RecvErr")
+	g.P("}")
+	g.P()
+	genAll(g, "f", fake, msg, false)
+}
+
+// genFakeHeader writes a header accessor, creating the header on first use so
+// the code under test can set values on it.
+func genFakeHeader(g *protogen.GeneratedFile, fake, method, field string) {
+	g.P("func (f *", fake, ") ", method, "() ", httpPackage.Ident("Header"), " {")
+	g.P("f.mu.Lock()")
+	g.P("defer f.mu.Unlock()")
+	g.P("if f.", field, " == nil {")
+	g.P("f.", field, " = make(", httpPackage.Ident("Header"), ")")
+	g.P("}")
+	g.P("return f.", field)
+	g.P("}")
+	g.P()
+}
+
+func genFakeClientConn(g *protogen.GeneratedFile, fake string) {
+	g.P("func (f *", fake, ") Conn() (", tripleProtocolPackage.Ident("StreamingClientConn"), ", error) {")
+	g.P("return nil, ", errorsPackage.Ident("New"), "(", strconv.Quote(fake+" has no connection"), ")")
+	g.P("}")
+	g.P()
+}
+
+// genFakeHandlerConn writes the Conn of a fake server stream, which shares the
+// headers of the fake.
+func genFakeHandlerConn(g *protogen.GeneratedFile, s Service, m Method, fake string) {
+	streamType := "StreamTypeBidi"
+	switch {
+	case !m.StreamsReturn:
+		streamType = "StreamTypeClient"
+	case !m.StreamsRequest:
+		streamType = "StreamTypeServer"
+	}
+	g.P("func (f *", fake, ") Conn() ", tripleProtocolPackage.Ident("StreamingHandlerConn"), " {")
+	g.P("return &", fakeHandlerConn(s), "{")
+	g.P("spec: ", tripleProtocolPackage.Ident("Spec"), "{StreamType: ", tripleProtocolPackage.Ident(streamType), ", Procedure: ", s.GoName, m.GoName, "Procedure},")
+	g.P("mu: &f.mu,")
+	g.P("reqHeader: &f.ReqHeader,")
+	g.P("respHeader: &f.RespHeader,")
+	g.P("respTrailer: &f.RespTrailer,")
+	g.P("}")
+	g.P("}")
+	g.P()
+}
+
+// genFakeHandlerConnType writes the type of the Conn of the fake server streams
+// of s. Its headers are the ones of the fake, while sending and receiving
+// through it fail, as there is no connection underneath.
+func genFakeHandlerConnType(g *protogen.GeneratedFile, s Service) {
+	conn := fakeHandlerConn(s)
+	header := httpPackage.Ident("Header")
+	g.P("// ", conn, " is the Conn of the fake server streams of ", s.GoName, ".")
+	g.P("type ", conn, " struct {")
+	g.P("spec ", tripleProtocolPackage.Ident("Spec"))
+	g.P("mu *", syncPackage.Ident("Mutex"))
+	g.P("reqHeader, respHeader, respTrailer *", header)
+	g.P("}")
+	g.P()
+	g.P("func (c *", conn, ") Spec() ", tripleProtocolPackage.Ident("Spec"), " {")
+	g.P("return c.spec")
+	g.P("}")
+	g.P()
+	g.P("func (c *", conn, ") Peer() ", tripleProtocolPackage.Ident("Peer"), " {")
+	g.P("return ", tripleProtocolPackage.Ident("Peer"), "{}")
+	g.P("}")
+	g.P()
+	g.P("func (c *", conn, ") Receive(interface{}) error {")
+	g.P("return c.err()")
+	g.P("}")
+	g.P()
+	g.P("func (c *", conn, ") RequestHeader() ", header, " {")
+	g.P("return c.header(c.reqHeader)")
+	g.P("}")
+	g.P()
+	g.P("func (c *", conn, ") ExportableHeader() ", header, " {")
+	g.P("return c.header(c.reqHeader)")
+	g.P("}")
+	g.P()
+	g.P("func (c *", conn, ") Send(interface{}) error {")
+	g.P("return c.err()")
+	g.P("}")
+	g.P()
+	g.P("func (c *", conn, ") ResponseHeader() ", header, " {")
+	g.P("return c.header(c.respHeader)")
+	g.P("}")
+	g.P()
+	g.P("func (c *", conn, ") ResponseTrailer() ", header, " {")
+	g.P("return c.header(c.respTrailer)")
+	g.P("}")
+	g.P()
+	g.P("func (c *", conn, ") header(h *", header, ") ", header, " {")
+	g.P("c.mu.Lock()")
+	g.P("defer c.mu.Unlock()")
+	g.P("if *h == nil {")
+	g.P("*h = make(", header, ")")
+	g.P("}")
+	g.P("return *h")
+	g.P("}")
+	g.P()
+	g.P("func (c *", conn, ") err() error {")
+	g.P("return ", errorsPackage.Ident("New"), "(", strconv.Quote("fake stream has no connection"), ")")
+	g.P("}")
+	g.P()
+}
+
+func fakeHandlerConn(s Service) string {
+	return unexported("Fake" + s.GoName + "HandlerConn")
+}
+
+// mockCall is the type recording a call of m.
+func mockCall(s Service, m Method) string {
+	return "Mock" + s.GoName + m.GoName + "Call"
+}
+
+// mockCallsField is the unexported field of a mock holding the calls of m.
+func mockCallsField(m Method) string {
+	return unexported(m.GoName + "Calls")
+}
+
+// mockMembers lists the members of the mocks of s, which are named after its
+// methods and may clash with each other.
+func mockMembers(s Service) []typeMembers {
+	if !*Mocks {
+		return nil
+	}
+	var types []typeMembers
+	add := func(mock string, embedded ...string) {
+		t := typeMembers{typeName: mock}
+		for _, name := range append([]string{"mu"}, embedded...) {
+			t.members = append(t.members, member{name: name})
+		}
+		for i := range s.Methods {
+			m := &s.Methods[i]
+			for _, name := range []string{m.GoName, m.GoName + "Func", mockCallsField(*m), m.GoName + "Calls", "Expect" + m.GoName + "Calls"} {
+				t.members = append(t.members, member{name: name, method: m})
+			}
+		}
+		types = append(types, t)
+	}
+	if *Client {
+		add("Mock" + s.GoName)
+	}
+	if *Server {
+		if *RequireUnimplemented {
+			add("Mock"+s.GoName+"Handler", "Unimplemented"+s.GoName+"Handler", "mustEmbedUnimplemented"+s.GoName+"Handler")
+		} else {
+			add("Mock" + s.GoName + "Handler")
+		}
+	}
+	return types
+}
@@ -0,0 +1,72 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenMockFile(t *testing.T) {
+	setParams(t, "mocks=true", "require_unimplemented_handlers=true")
+	plugin := newPlugin(t, newRequest(t, `
+		name: "greet.proto"
+		package: "greet"
+		options { go_package: "example.com/greet" }
+		`+greetMessages+greetStreams))
+	file := plugin.FilesByPath["greet.proto"]
+	tripleGo, err := ProcessProtoFile(plugin, file)
+	if err != nil {
+		t.Fatalf("ProcessProtoFile: %v", err)
+	}
+	tripleGo.Package = string(file.GoPackageName)
+	g := plugin.NewGeneratedFile("greet.triple_mock.go", file.GoImportPath)
+	if err := GenMockFile(g, tripleGo); err != nil {
+		t.Fatalf("GenMockFile: %v", err)
+	}
+	content, err := g.Content()
+	if err != nil {
+		t.Fatalf("generated mocks do not parse: %v", err)
+	}
+	mocks := string(content)
+
+	for _, want := range []string{
+		"type MockGreetService struct",
+		"type MockGreetServiceHandler struct",
+		"func (m *MockGreetService) ExpectGreetBidiCalls(",
+		"type FakeGreetServiceGreetClientClient struct",
+		"type FakeGreetServiceGreetBidiServer struct",
+		// Fake server streams have a Conn sharing their headers.
+		"type fakeGreetServiceHandlerConn struct {",
+		"func (c *fakeGreetServiceHandlerConn) ExportableHeader() http.Header {",
+		"reqHeader:   &f.ReqHeader,",
+	} {
+		if !strings.Contains(mocks, want) {
+			t.Errorf("generated mocks lack %q", want)
+		}
+	}
+	if strings.Contains(mocks, "StreamingHandlerConn {\n\treturn nil\n}") {
+		t.Error("Conn of a fake server stream returns nil")
+	}
+	// An unscripted CloseAndRecv must not return a nil message without an error.
+	closeAndRecv := mocks[strings.Index(mocks, "func (f *FakeGreetServiceGreetClientClient) CloseAndRecv()"):]
+	closeAndRecv = closeAndRecv[:strings.Index(closeAndRecv, "\n}\n")]
+	if !strings.Contains(closeAndRecv, `"FakeGreetServiceGreetClientClient.Response is not scripted"`) {
+		t.Errorf("CloseAndRecv does not fail when unscripted:\n%s", closeAndRecv)
+	}
+}
@@ -0,0 +1,383 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Values of the openapi parameter.
+const (
+	OpenAPIPerFile = "true"
+	OpenAPIMerged  = "merged"
+)
+
+// OpenAPIMergedFilename is the document written when the openapi parameter is
+// "merged".
+const OpenAPIMergedFilename = "triple.openapi.yaml"
+
+const errorSchema = "triple.Error"
+
+// OpenAPIFilename returns the name of the OpenAPI document generated for file.
+func OpenAPIFilename(file *protogen.File) string {
+	return GeneratedFilenamePrefix(file) + ".triple.openapi.yaml"
+}
+
+// GenOpenAPIFile writes an OpenAPI 3 document describing the unary methods of
+// the services in triples, which triple serves as HTTP POST requests with a
+// JSON body on the path of their Procedure constant. Streaming methods have no
+// plain HTTP equivalent and are left out.
+func GenOpenAPIFile(genFile *protogen.GeneratedFile, title string, triples []TripleGo) error {
+	paths := yamlMap{}
+	messages := make(map[protoreflect.FullName]*protogen.Message)
+	var sources []string
+	for _, t := range triples {
+		sources = append(sources, t.Source)
+		for _, s := range t.Services {
+			for _, m := range s.Methods {
+				if m.isStream() {
+					continue
+				}
+				collectMessages(messages, m.Input)
+				collectMessages(messages, m.Output)
+				paths = append(paths, yamlEntry{"/" + s.FullName + "/" + m.MethodName, yamlMap{{"post", openAPIOperation(s, m)}}})
+			}
+		}
+	}
+
+	schemas := yamlMap{}
+	names := make([]string, 0, len(messages))
+	for name := range messages {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		schemas = append(schemas, yamlEntry{name, messageSchema(messages[protoreflect.FullName(name)])})
+	}
+	schemas = append(schemas, yamlEntry{errorSchema, yamlMap{
+		{"type", "object"},
+		{"description", "The error returned by a failed call."},
+		{"properties", yamlMap{
+			{"code", yamlMap{{"type", "string"}, {"description", "The triple error code, like not_found."}}},
+			{"message", yamlMap{{"type", "string"}}},
+			{"details", yamlMap{{"type", "array"}, {"items", yamlMap{{"type", "object"}}}}},
+		}},
+	}})
+
+	genFile.P("# Code generated by protoc-gen-triple. DO NOT EDIT.")
+	genFile.P("#")
+	genFile.P("# Source: ", strings.Join(sources, ", "))
+	return writeYAML(genFile, yamlMap{
+		{"openapi", "3.0.3"},
+		{"info", yamlMap{{"title", title}, {"version", apiVersion(triples)}}},
+		{"paths", paths},
+		{"components", yamlMap{{"schemas", schemas}}},
+	}, 0)
+}
+
+func openAPIOperation(s Service, m Method) yamlMap {
+	op := yamlMap{
+		{"tags", []interface{}{s.FullName}},
+		{"operationId", s.FullName + "." + m.MethodName},
+	}
+	if doc := commentText(m.Comments.Leading); doc != "" {
+		op = append(op, yamlEntry{"description", doc})
+	}
+	if m.Deprecated {
+		op = append(op, yamlEntry{"deprecated", true})
+	}
+	return append(op,
+		yamlEntry{"requestBody", yamlMap{
+			{"required", true},
+			{"content", jsonContent(schemaRef(m.Input.Desc.FullName()))},
+		}},
+		yamlEntry{"responses", yamlMap{
+			{"200", yamlMap{{"description", "OK"}, {"content", jsonContent(schemaRef(m.Output.Desc.FullName()))}}},
+			{"default", yamlMap{{"description", "Error"}, {"content", jsonContent(schemaRef(errorSchema))}}},
+		}},
+	)
+}
+
+func jsonContent(schema yamlMap) yamlMap {
+	return yamlMap{{"application/json", yamlMap{{"schema", schema}}}}
+}
+
+func schemaRef(name protoreflect.FullName) yamlMap {
+	return yamlMap{{"$ref", "#/components/schemas/" + string(name)}}
+}
+
+// collectMessages adds message and every message its fields refer to. Well-known
+// types with a special JSON mapping are inlined and not collected.
+func collectMessages(messages map[protoreflect.FullName]*protogen.Message, message *protogen.Message) {
+	if _, ok := wellKnownSchema(message.Desc.FullName()); ok {
+		return
+	}
+	if _, ok := messages[message.Desc.FullName()]; ok {
+		return
+	}
+	messages[message.Desc.FullName()] = message
+	for _, field := range message.Fields {
+		if field.Desc.IsMap() {
+			field = field.Message.Fields[1]
+		}
+		if field.Message != nil {
+			collectMessages(messages, field.Message)
+		}
+	}
+}
+
+func messageSchema(message *protogen.Message) yamlMap {
+	schema := yamlMap{{"type", "object"}}
+	if doc := commentText(message.Comments.Leading); doc != "" {
+		schema = append(schema, yamlEntry{"description", doc})
+	}
+	properties := yamlMap{}
+	for _, field := range message.Fields {
+		properties = append(properties, yamlEntry{field.Desc.JSONName(), fieldSchema(field)})
+	}
+	if len(properties) > 0 {
+		schema = append(schema, yamlEntry{"properties", properties})
+	}
+	return schema
+}
+
+func fieldSchema(field *protogen.Field) yamlMap {
+	var schema yamlMap
+	switch {
+	case field.Desc.IsMap():
+		schema = yamlMap{{"type", "object"}, {"additionalProperties", singularSchema(field.Message.Fields[1])}}
+	case field.Desc.IsList():
+		schema = yamlMap{{"type", "array"}, {"items", singularSchema(field)}}
+	default:
+		schema = singularSchema(field)
+	}
+	if doc := commentText(field.Comments.Leading); doc != "" {
+		if _, isRef := schema.get("$ref"); isRef {
+			// Siblings of $ref are ignored in OpenAPI 3.0.
+			schema = yamlMap{{"allOf", []interface{}{schema}}}
+		}
+		schema = append(schema, yamlEntry{"description", doc})
+	}
+	if field.Desc.Options().(*descriptorpb.FieldOptions).GetDeprecated() {
+		schema = append(schema, yamlEntry{"deprecated", true})
+	}
+	return schema
+}
+
+// singularSchema is the schema of a single value of field, following the
+// proto3 JSON mapping.
+func singularSchema(field *protogen.Field) yamlMap {
+	switch field.Desc.Kind() {
+	case protoreflect.BoolKind:
+		return yamlMap{{"type", "boolean"}}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return yamlMap{{"type", "integer"}, {"format", "int32"}}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return yamlMap{{"type", "integer"}, {"format", "int64"}, {"minimum", 0}}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return yamlMap{{"type", "string"}, {"format", "int64"}}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return yamlMap{{"type", "string"}, {"format", "uint64"}}
+	case protoreflect.FloatKind:
+		return yamlMap{{"type", "number"}, {"format", "float"}}
+	case protoreflect.DoubleKind:
+		return yamlMap{{"type", "number"}, {"format", "double"}}
+	case protoreflect.StringKind:
+		return yamlMap{{"type", "string"}}
+	case protoreflect.BytesKind:
+		return yamlMap{{"type", "string"}, {"format", "byte"}}
+	case protoreflect.EnumKind:
+		var values []interface{}
+		for _, value := range field.Enum.Values {
+			values = append(values, string(value.Desc.Name()))
+		}
+		return yamlMap{{"type", "string"}, {"enum", values}}
+	default:
+		if schema, ok := wellKnownSchema(field.Message.Desc.FullName()); ok {
+			return schema
+		}
+		return schemaRef(field.Message.Desc.FullName())
+	}
+}
+
+// wellKnownSchema returns the schema of the well-known types whose JSON form is
+// not a plain object of their fields.
+func wellKnownSchema(name protoreflect.FullName) (yamlMap, bool) {
+	switch name {
+	case "google.protobuf.Timestamp":
+		return yamlMap{{"type", "string"}, {"format", "date-time"}}, true
+	case "google.protobuf.Duration":
+		return yamlMap{{"type", "string"}, {"pattern", `^-?[0-9]+(\.[0-9]+)?s$`}}, true
+	case "google.protobuf.FieldMask":
+		return yamlMap{{"type", "string"}}, true
+	case "google.protobuf.Struct", "google.protobuf.Empty":
+		return yamlMap{{"type", "object"}}, true
+	case "google.protobuf.Any":
+		return yamlMap{{"type", "object"}, {"properties", yamlMap{{"@type", yamlMap{{"type", "string"}}}}}}, true
+	case "google.protobuf.Value":
+		return yamlMap{}, true
+	case "google.protobuf.ListValue":
+		return yamlMap{{"type", "array"}, {"items", yamlMap{}}}, true
+	case "google.protobuf.BoolValue":
+		return yamlMap{{"type", "boolean"}}, true
+	case "google.protobuf.StringValue":
+		return yamlMap{{"type", "string"}}, true
+	case "google.protobuf.BytesValue":
+		return yamlMap{{"type", "string"}, {"format", "byte"}}, true
+	case "google.protobuf.Int32Value", "google.protobuf.UInt32Value":
+		return yamlMap{{"type", "integer"}}, true
+	case "google.protobuf.Int64Value", "google.protobuf.UInt64Value":
+		return yamlMap{{"type", "string"}, {"format", "int64"}}, true
+	case "google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return yamlMap{{"type", "number"}}, true
+	}
+	return nil, false
+}
+
+// commentText returns the text of a proto comment without the space that
+// usually follows the comment markers.
+func commentText(c protogen.Comments) string {
+	lines := strings.Split(strings.TrimSpace(string(c)), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+var versionSegment = regexp.MustCompile(`^v\d+((alpha|beta)\d*)?$`)
+
+// apiVersion takes the API version from the last segment of the proto package,
+// like v1 for greet.v1, when all triples agree on one.
+func apiVersion(triples []TripleGo) string {
+	version := ""
+	for _, t := range triples {
+		segments := strings.Split(t.ProtoPackage, ".")
+		last := segments[len(segments)-1]
+		if !versionSegment.MatchString(last) || (version != "" && version != last) {
+			return "0.0.0"
+		}
+		version = last
+	}
+	if version == "" {
+		return "0.0.0"
+	}
+	return version
+}
+
+// yamlMap is a YAML mapping that keeps the order of its entries.
+type yamlMap []yamlEntry
+
+type yamlEntry struct {
+	Key   string
+	Value interface{}
+}
+
+func (m yamlMap) get(key string) (interface{}, bool) {
+	for _, e := range m {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// writeYAML writes m in block style. Keys and strings are quoted as JSON
+// strings, which YAML reads the same way. It fails on values of other types
+// than yamlMap, []interface{}, string, bool and int.
+func writeYAML(g *protogen.GeneratedFile, m yamlMap, indent int) error {
+	prefix := strings.Repeat("  ", indent)
+	for _, e := range m {
+		key := prefix + yamlKey(e.Key) + ":"
+		switch v := e.Value.(type) {
+		case yamlMap:
+			if len(v) == 0 {
+				g.P(key, " {}")
+				continue
+			}
+			g.P(key)
+			if err := writeYAML(g, v, indent+1); err != nil {
+				return err
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				g.P(key, " []")
+				continue
+			}
+			g.P(key)
+			for _, item := range v {
+				if itemMap, ok := item.(yamlMap); ok {
+					g.P(prefix, "  -")
+					if err := writeYAML(g, itemMap, indent+2); err != nil {
+						return err
+					}
+					continue
+				}
+				scalar, err := yamlScalar(item)
+				if err != nil {
+					return fmt.Errorf("%s: %w", e.Key, err)
+				}
+				g.P(prefix, "  - ", scalar)
+			}
+		default:
+			scalar, err := yamlScalar(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", e.Key, err)
+			}
+			g.P(key, " ", scalar)
+		}
+	}
+	return nil
+}
+
+var plainKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// yamlKey quotes key unless it is a plain word. Words YAML 1.1 reads as null or
+// booleans, like the JSON name of a field named on, are quoted too.
+func yamlKey(key string) string {
+	if plainKey.MatchString(key) && !yamlReserved[strings.ToLower(key)] {
+		return key
+	}
+	return strconv.Quote(key)
+}
+
+var yamlReserved = map[string]bool{
+	"null": true, "true": true, "false": true,
+	"yes": true, "no": true, "on": true, "off": true, "y": true, "n": true,
+}
+
+func yamlScalar(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	}
+	return "", fmt.Errorf("unsupported YAML value %T", v)
+}
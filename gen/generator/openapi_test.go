@@ -0,0 +1,429 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+const timestampProto = `
+	name: "google/protobuf/timestamp.proto"
+	package: "google.protobuf"
+	options { go_package: "google.golang.org/protobuf/types/known/timestamppb" }
+	message_type {
+		name: "Timestamp"
+		field { name: "seconds" number: 1 label: LABEL_OPTIONAL type: TYPE_INT64 }
+		field { name: "nanos" number: 2 label: LABEL_OPTIONAL type: TYPE_INT32 }
+	}`
+
+const libraryProto = `
+	name: "library.proto"
+	package: "library.v1"
+	dependency: "google/protobuf/timestamp.proto"
+	options { go_package: "example.com/library" }
+	enum_type {
+		name: "Genre"
+		value { name: "GENRE_UNSPECIFIED" number: 0 }
+		value { name: "FICTION" number: 1 }
+	}
+	message_type {
+		name: "Book"
+		field { name: "title" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING }
+		field { name: "page_count" number: 2 label: LABEL_OPTIONAL type: TYPE_INT32 }
+		field { name: "isbn" number: 3 label: LABEL_OPTIONAL type: TYPE_INT64 }
+		field { name: "genre" number: 4 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".library.v1.Genre" }
+		field { name: "labels" number: 5 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".library.v1.Book.LabelsEntry" }
+		field { name: "authors" number: 6 label: LABEL_REPEATED type: TYPE_STRING }
+		field { name: "published" number: 7 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Timestamp" }
+		field { name: "on" number: 8 label: LABEL_OPTIONAL type: TYPE_BOOL }
+		field { name: "related" number: 9 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".library.v1.Book.RelatedEntry" }
+		nested_type {
+			name: "LabelsEntry"
+			field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING }
+			field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_INT32 }
+			options { map_entry: true }
+		}
+		nested_type {
+			name: "RelatedEntry"
+			field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING }
+			field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".library.v1.Book" }
+			options { map_entry: true }
+		}
+	}
+	message_type {
+		name: "GetBookRequest"
+		field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING }
+	}
+	service {
+		name: "LibraryService"
+		method { name: "GetBook" input_type: ".library.v1.GetBookRequest" output_type: ".library.v1.Book" }
+		method {
+			name: "WatchBook" input_type: ".library.v1.GetBookRequest" output_type: ".library.v1.Book"
+			server_streaming: true
+		}
+	}`
+
+// genOpenAPI returns the OpenAPI document of the files named names in plugin,
+// and the document parsed back.
+func genOpenAPI(t *testing.T, plugin *protogen.Plugin, title string, names ...string) (string, map[string]interface{}) {
+	t.Helper()
+	var triples []TripleGo
+	for _, name := range names {
+		tripleGo, err := ProcessProtoFile(plugin, plugin.FilesByPath[name])
+		if err != nil {
+			t.Fatalf("ProcessProtoFile: %v", err)
+		}
+		triples = append(triples, tripleGo)
+	}
+	g := plugin.NewGeneratedFile(OpenAPIMergedFilename, "")
+	if err := GenOpenAPIFile(g, title, triples); err != nil {
+		t.Fatalf("GenOpenAPIFile: %v", err)
+	}
+	content, err := g.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseYAML(string(content))
+	if err != nil {
+		t.Fatalf("parsing the OpenAPI document: %v\n%s", err, content)
+	}
+	return string(content), doc
+}
+
+// lookup returns the value at the path of keys in doc.
+func lookup(t *testing.T, doc map[string]interface{}, keys ...string) interface{} {
+	t.Helper()
+	var v interface{} = doc
+	for i, key := range keys {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			t.Fatalf("%s is not a mapping", strings.Join(keys[:i], "."))
+		}
+		if v, ok = m[key]; !ok {
+			t.Fatalf("no %s", strings.Join(keys[:i+1], "."))
+		}
+	}
+	return v
+}
+
+func TestGenOpenAPIFile(t *testing.T) {
+	setParams(t, "openapi=true")
+	plugin := newPlugin(t, newRequest(t, timestampProto, libraryProto))
+	// The title needs quoting: it contains a colon, quotes, a newline and a
+	// comment marker.
+	title := "Library: \"v1\"\n# yes"
+	_, doc := genOpenAPI(t, plugin, title, "library.proto")
+
+	if got := lookup(t, doc, "openapi"); got != "3.0.3" {
+		t.Errorf("openapi = %v", got)
+	}
+	if got := lookup(t, doc, "info", "title"); got != title {
+		t.Errorf("title = %q, want %q", got, title)
+	}
+	if got := lookup(t, doc, "info", "version"); got != "v1" {
+		t.Errorf("version = %v, want v1", got)
+	}
+
+	paths := lookup(t, doc, "paths").(map[string]interface{})
+	if len(paths) != 1 {
+		t.Errorf("paths = %v, want only the unary GetBook", paths)
+	}
+	op := lookup(t, doc, "paths", "/library.v1.LibraryService/GetBook", "post")
+	want := map[string]interface{}{
+		"tags":        []interface{}{"library.v1.LibraryService"},
+		"operationId": "library.v1.LibraryService.GetBook",
+		"requestBody": map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{"$ref": "#/components/schemas/library.v1.GetBookRequest"},
+			}},
+		},
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{"description": "OK", "content": map[string]interface{}{"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{"$ref": "#/components/schemas/library.v1.Book"},
+			}}},
+			"default": map[string]interface{}{"description": "Error", "content": map[string]interface{}{"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{"$ref": "#/components/schemas/triple.Error"},
+			}}},
+		},
+	}
+	if !reflect.DeepEqual(op, want) {
+		t.Errorf("GetBook operation = %#v, want %#v", op, want)
+	}
+
+	schemas := lookup(t, doc, "components", "schemas").(map[string]interface{})
+	var names []string
+	for name := range schemas {
+		names = append(names, name)
+	}
+	// Map entries and well-known types are not schemas of their own.
+	if len(names) != 3 || schemas["library.v1.Book"] == nil || schemas["library.v1.GetBookRequest"] == nil || schemas[errorSchema] == nil {
+		t.Errorf("schemas = %v, want the messages and the error", names)
+	}
+	book := lookup(t, doc, "components", "schemas", "library.v1.Book", "properties")
+	wantBook := map[string]interface{}{
+		"title":     map[string]interface{}{"type": "string"},
+		"pageCount": map[string]interface{}{"type": "integer", "format": "int32"},
+		"isbn":      map[string]interface{}{"type": "string", "format": "int64"},
+		"genre":     map[string]interface{}{"type": "string", "enum": []interface{}{"GENRE_UNSPECIFIED", "FICTION"}},
+		"labels": map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": "integer", "format": "int32"},
+		},
+		"authors":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		"published": map[string]interface{}{"type": "string", "format": "date-time"},
+		"on":        map[string]interface{}{"type": "boolean"},
+		"related": map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"$ref": "#/components/schemas/library.v1.Book"},
+		},
+	}
+	if !reflect.DeepEqual(book, wantBook) {
+		t.Errorf("Book properties = %#v, want %#v", book, wantBook)
+	}
+}
+
+func TestGenOpenAPIFileMerged(t *testing.T) {
+	setParams(t, "openapi=merged")
+	plugin := newPlugin(t, newRequest(t, timestampProto, libraryProto, `
+		name: "greet.proto"
+		package: "greet"
+		options { go_package: "example.com/greet" }
+		`+greetMessages+`
+		service { name: "GreetService" `+rpc("Greet")+` }`))
+
+	// Per file, each document describes the services of its file.
+	if got := OpenAPIFilename(plugin.FilesByPath["library.proto"]); got != "example.com/library/library.triple.openapi.yaml" {
+		t.Errorf("OpenAPIFilename = %s", got)
+	}
+	content, doc := genOpenAPI(t, plugin, "greet", "greet.proto")
+	if paths := lookup(t, doc, "paths").(map[string]interface{}); len(paths) != 1 || paths["/greet.GreetService/Greet"] == nil {
+		t.Errorf("paths of greet.proto = %v", paths)
+	}
+	if !strings.Contains(content, "# Source: greet.proto\n") {
+		t.Errorf("document of greet.proto lacks its source:\n%s", content)
+	}
+
+	// Merged, one document describes the services of all files.
+	content, doc = genOpenAPI(t, plugin, "Triple services", "library.proto", "greet.proto")
+	paths := lookup(t, doc, "paths").(map[string]interface{})
+	for _, path := range []string{"/library.v1.LibraryService/GetBook", "/greet.GreetService/Greet"} {
+		if paths[path] == nil {
+			t.Errorf("merged document lacks %s", path)
+		}
+	}
+	schemas := lookup(t, doc, "components", "schemas").(map[string]interface{})
+	for _, name := range []string{"library.v1.Book", "greet.GreetRequest", "greet.GreetResponse", errorSchema} {
+		if schemas[name] == nil {
+			t.Errorf("merged document lacks the %s schema", name)
+		}
+	}
+	// The packages disagree on the API version.
+	if got := lookup(t, doc, "info", "version"); got != "0.0.0" {
+		t.Errorf("version = %v, want 0.0.0", got)
+	}
+	if !strings.Contains(content, "# Source: library.proto, greet.proto\n") {
+		t.Errorf("merged document lacks its sources:\n%s", content)
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	plugin := newPlugin(t, newRequest(t))
+	g := plugin.NewGeneratedFile("test.yaml", "")
+	err := writeYAML(g, yamlMap{
+		{"null", "null"},
+		{"on", true},
+		{"200", 200},
+		{"@type", "123"},
+		{"list", []interface{}{"a: b", yamlMap{{"key", "#value"}}, 0}},
+		{"empty", yamlMap{}},
+		{"none", []interface{}{}},
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := g.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseYAML(string(content))
+	if err != nil {
+		t.Fatalf("parsing: %v\n%s", err, content)
+	}
+	want := map[string]interface{}{
+		"null":  "null",
+		"on":    true,
+		"200":   200,
+		"@type": "123",
+		"list":  []interface{}{"a: b", map[string]interface{}{"key": "#value"}, 0},
+		"empty": map[string]interface{}{},
+		"none":  []interface{}{},
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("parsed %#v, want %#v\n%s", doc, want, content)
+	}
+
+	if err := writeYAML(g, yamlMap{{"minimum", 0.5}}, 0); err == nil {
+		t.Error("writeYAML accepted a float")
+	}
+	if err := writeYAML(g, yamlMap{{"enum", []interface{}{nil}}}, 0); err == nil {
+		t.Error("writeYAML accepted nil")
+	}
+}
+
+// parseYAML parses the block style YAML writeYAML writes. It is strict: plain
+// scalars other than booleans and integers, and plain keys YAML reads as
+// something else than a string, are errors, so that the document reads the
+// same with any YAML parser.
+func parseYAML(doc string) (map[string]interface{}, error) {
+	p := &yamlParser{}
+	for _, line := range strings.Split(doc, "\n") {
+		text := strings.TrimLeft(line, " ")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		p.lines = append(p.lines, yamlLine{indent: len(line) - len(text), text: text})
+	}
+	m, err := p.mapping(0)
+	if err == nil && p.pos < len(p.lines) {
+		err = fmt.Errorf("unexpected line %q", p.lines[p.pos].text)
+	}
+	return m, err
+}
+
+type yamlLine struct {
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func (p *yamlParser) at(indent int) bool {
+	return p.pos < len(p.lines) && p.lines[p.pos].indent == indent
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for p.at(indent) && !strings.HasPrefix(p.lines[p.pos].text, "-") {
+		key, rest, err := parseYAMLKey(p.lines[p.pos].text)
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+		if _, ok := m[key]; ok {
+			return nil, fmt.Errorf("duplicate key %q", key)
+		}
+		switch {
+		case rest != "":
+			m[key], err = parseYAMLScalar(rest)
+		case p.at(indent+2) && strings.HasPrefix(p.lines[p.pos].text, "-"):
+			m[key], err = p.sequence(indent + 2)
+		case p.at(indent + 2):
+			m[key], err = p.mapping(indent + 2)
+		default:
+			err = fmt.Errorf("key %q has no value", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (p *yamlParser) sequence(indent int) ([]interface{}, error) {
+	var s []interface{}
+	for p.at(indent) && strings.HasPrefix(p.lines[p.pos].text, "-") {
+		text := p.lines[p.pos].text
+		p.pos++
+		var item interface{}
+		var err error
+		if text == "-" {
+			item, err = p.mapping(indent + 2)
+		} else if strings.HasPrefix(text, "- ") {
+			item, err = parseYAMLScalar(text[2:])
+		} else {
+			err = fmt.Errorf("malformed item %q", text)
+		}
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, item)
+	}
+	return s, nil
+}
+
+func parseYAMLKey(text string) (key, rest string, err error) {
+	if strings.HasPrefix(text, `"`) {
+		quoted, err := strconv.QuotedPrefix(text)
+		if err != nil {
+			return "", "", fmt.Errorf("key of %q: %v", text, err)
+		}
+		key, _ = strconv.Unquote(quoted)
+		rest = text[len(quoted):]
+	} else {
+		i := strings.Index(text, ":")
+		if i < 0 {
+			return "", "", fmt.Errorf("no key in %q", text)
+		}
+		key, rest = text[:i], text[i:]
+		switch strings.ToLower(key) {
+		case "null", "~", "true", "false", "yes", "no", "on", "off", "y", "n":
+			return "", "", fmt.Errorf("plain key %q is not a string", key)
+		}
+		if strings.ContainsAny(key, " #\"'{}[]&*!|>%@`") {
+			return "", "", fmt.Errorf("plain key %q needs quoting", key)
+		}
+	}
+	if !strings.HasPrefix(rest, ":") {
+		return "", "", fmt.Errorf("no colon after key in %q", text)
+	}
+	return key, strings.TrimPrefix(rest[1:], " "), nil
+}
+
+func parseYAMLScalar(text string) (interface{}, error) {
+	switch text {
+	case "{}":
+		return map[string]interface{}{}, nil
+	case "[]":
+		return []interface{}{}, nil
+	case "true", "false":
+		return text == "true", nil
+	}
+	if strings.HasPrefix(text, `"`) {
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("string %s: %v", text, err)
+		}
+		return s, nil
+	}
+	if n, err := strconv.Atoi(text); err == nil {
+		return n, nil
+	}
+	return nil, fmt.Errorf("plain scalar %q", text)
+}
@@ -0,0 +1,217 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+import (
+	"github.com/dubbogo/protoc-gen-go-triple/v3/dubbo"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const timePackage = protogen.GoImportPath("time")
+
+// Meta keys of the defaults read from the dubbo.service and dubbo.method options.
+const (
+	groupMeta       = "group"
+	versionMeta     = "version"
+	timeoutMeta     = "timeout"
+	retriesMeta     = "retries"
+	loadBalanceMeta = "loadbalance"
+)
+
+// DubboOptions are the defaults a service or method declares with the
+// dubbo.service and dubbo.method options of dubbo/options.proto. Methods only
+// declare Timeout and Retries.
+type DubboOptions struct {
+	Group       string
+	Version     string
+	Timeout     time.Duration
+	Retries     *int32
+	LoadBalance string
+}
+
+func (o DubboOptions) empty() bool {
+	return o == DubboOptions{}
+}
+
+// serviceOptions reads the dubbo.service option of service.
+func serviceOptions(service *protogen.Service) (DubboOptions, error) {
+	ext, _ := proto.GetExtension(service.Desc.Options(), dubbo.E_Service).(*dubbo.ServiceOptions)
+	if ext == nil {
+		return DubboOptions{}, nil
+	}
+	timeout, err := parseTimeout(service.Desc.FullName(), ext.GetTimeout())
+	if err != nil {
+		return DubboOptions{}, err
+	}
+	return DubboOptions{
+		Group:       ext.GetGroup(),
+		Version:     ext.GetVersion(),
+		Timeout:     timeout,
+		Retries:     ext.Retries,
+		LoadBalance: ext.GetLoadbalance(),
+	}, nil
+}
+
+// methodOptions reads the dubbo.method option of method.
+func methodOptions(method *protogen.Method) (DubboOptions, error) {
+	ext, _ := proto.GetExtension(method.Desc.Options(), dubbo.E_Method).(*dubbo.MethodOptions)
+	if ext == nil {
+		return DubboOptions{}, nil
+	}
+	timeout, err := parseTimeout(method.Desc.FullName(), ext.GetTimeout())
+	if err != nil {
+		return DubboOptions{}, err
+	}
+	return DubboOptions{Timeout: timeout, Retries: ext.Retries}, nil
+}
+
+func parseTimeout(name protoreflect.FullName, timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(timeout)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("dubbo option of %s: timeout %q is not a positive duration", name, timeout)
+	}
+	return d, nil
+}
+
+// durationExpr writes d as a Go expression in the largest unit that divides it.
+func durationExpr(g *protogen.GeneratedFile, d time.Duration) string {
+	for _, unit := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"Hour", time.Hour},
+		{"Minute", time.Minute},
+		{"Second", time.Second},
+		{"Millisecond", time.Millisecond},
+		{"Microsecond", time.Microsecond},
+	} {
+		if d%unit.d == 0 {
+			return fmt.Sprintf("%d * %s", d/unit.d, g.QualifiedGoIdent(timePackage.Ident(unit.name)))
+		}
+	}
+	return fmt.Sprintf("%s(%d)", g.QualifiedGoIdent(timePackage.Ident("Duration")), int64(d))
+}
+
+// genReferenceDefaults prepends the service defaults to the ReferenceOptions of
+// New{Service}, so that the options passed by the caller override them.
+func genReferenceDefaults(g *protogen.GeneratedFile, s Service) {
+	o := s.Options
+	if o.empty() {
+		return
+	}
+	g.P("// Defaults of the dubbo.service option, overridden by opts.")
+	g.P("opts = append([]", clientPackage.Ident("ReferenceOption"), "{")
+	if o.Group != "" {
+		g.P(clientPackage.Ident("WithGroup"), "(", strconv.Quote(o.Group), "),")
+	}
+	if o.Version != "" {
+		g.P(clientPackage.Ident("WithVersion"), "(", strconv.Quote(o.Version), "),")
+	}
+	if o.Timeout != 0 {
+		g.P(clientPackage.Ident("WithRequestTimeout"), "(", durationExpr(g, o.Timeout), "),")
+	}
+	if o.Retries != nil {
+		g.P(clientPackage.Ident("WithRetries"), "(", *o.Retries, "),")
+	}
+	if o.LoadBalance != "" {
+		g.P(clientPackage.Ident("WithLoadBalance"), "(", strconv.Quote(o.LoadBalance), "),")
+	}
+	g.P("}, opts...)")
+}
+
+// genCallDefaults prepends the method defaults to the CallOptions of a client
+// method, so that the options passed by the caller override them. With
+// idempotent_retries, unary methods safe to repeat are retried unless the
+// service or the method sets the retries.
+func genCallDefaults(g *protogen.GeneratedFile, s Service, m Method) {
+	o := m.Options
+	retries := retriesByDefault(s, m)
+	if o.Timeout == 0 && o.Retries == nil && !retries {
+		return
+	}
+	g.P("opts = append([]", clientPackage.Ident("CallOption"), "{")
+	if o.Timeout != 0 {
+		g.P(clientPackage.Ident("WithCallRequestTimeout"), "(", durationExpr(g, o.Timeout), "),")
+	}
+	if o.Retries != nil {
+		g.P(clientPackage.Ident("WithCallRetries"), "(", *o.Retries, "),")
+	}
+	if retries {
+		g.P(clientPackage.Ident("WithCallRetries"), "(", *IdempotentRetries, "),")
+	}
+	g.P("}, opts...)")
+}
+
+// genServiceDefaults prepends the service defaults to the ServiceOptions of
+// Register{Service}Handler, so that the options passed by the caller override
+// them. The timeout is a client setting and is only published in the metadata.
+func genServiceDefaults(g *protogen.GeneratedFile, s Service) {
+	o := s.Options
+	if o.Group == "" && o.Version == "" && o.Retries == nil && o.LoadBalance == "" {
+		return
+	}
+	g.P("// Defaults of the dubbo.service option, overridden by opts.")
+	g.P("opts = append([]", serverPackage.Ident("ServiceOption"), "{")
+	if o.Group != "" {
+		g.P(serverPackage.Ident("WithGroup"), "(", strconv.Quote(o.Group), "),")
+	}
+	if o.Version != "" {
+		g.P(serverPackage.Ident("WithVersion"), "(", strconv.Quote(o.Version), "),")
+	}
+	if o.Retries != nil {
+		g.P(serverPackage.Ident("WithRetries"), "(", *o.Retries, "),")
+	}
+	if o.LoadBalance != "" {
+		g.P(serverPackage.Ident("WithLoadBalance"), "(", strconv.Quote(o.LoadBalance), "),")
+	}
+	g.P("}, opts...)")
+}
+
+// genOptionsMeta writes the entries of o into a ClientInfo, ServiceInfo or
+// MethodInfo Meta map literal.
+func genOptionsMeta(g *protogen.GeneratedFile, o DubboOptions) {
+	if o.Group != "" {
+		g.P(strconv.Quote(groupMeta), ": ", strconv.Quote(o.Group), ",")
+	}
+	if o.Version != "" {
+		g.P(strconv.Quote(versionMeta), ": ", strconv.Quote(o.Version), ",")
+	}
+	if o.Timeout != 0 {
+		g.P(strconv.Quote(timeoutMeta), ": ", durationExpr(g, o.Timeout), ",")
+	}
+	if o.Retries != nil {
+		g.P(strconv.Quote(retriesMeta), ": ", *o.Retries, ",")
+	}
+	if o.LoadBalance != "" {
+		g.P(strconv.Quote(loadBalanceMeta), ": ", strconv.Quote(o.LoadBalance), ",")
+	}
+}
@@ -0,0 +1,105 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/dubbogo/protoc-gen-go-triple/v3/dubbo"
+)
+
+// dubboOptionsProto declares a service with the dubbo.service option, whose
+// Greet method sets the dubbo.method option and whose Plain method does not.
+var dubboOptionsProto = `
+	name: "greet.proto"
+	package: "greet"
+	options { go_package: "example.com/greet" }
+	` + greetMessages + `
+	service {
+		name: "GreetService"
+		options { [dubbo.service] { group: "greet" version: "1.0.0" timeout: "3s" retries: 2 loadbalance: "roundrobin" } }
+		method {
+			name: "Greet" input_type: ".greet.GreetRequest" output_type: ".greet.GreetResponse"
+			options { [dubbo.method] { timeout: "1500ms" retries: 0 } }
+		}
+		` + rpc("Plain") + `
+	}`
+
+func TestGenDubboOptions(t *testing.T) {
+	setParams(t)
+	stubs := generate(t, newPlugin(t, newRequest(t, dubboOptionsProto)), "greet.proto")
+	for _, want := range []string{
+		// The defaults come before the options of the caller, which override them.
+		"opts = append([]client.ReferenceOption{\n\t\tclient.WithGroup(\"greet\"),\n\t\tclient.WithVersion(\"1.0.0\"),\n\t\tclient.WithRequestTimeout(3 * time.Second),\n\t\tclient.WithRetries(2),\n\t\tclient.WithLoadBalance(\"roundrobin\"),\n\t}, opts...)",
+		"opts = append([]client.CallOption{\n\t\tclient.WithCallRequestTimeout(1500 * time.Millisecond),\n\t\tclient.WithCallRetries(0),\n\t}, opts...)",
+		// The timeout is a client setting.
+		"opts = append([]server.ServiceOption{\n\t\tserver.WithGroup(\"greet\"),\n\t\tserver.WithVersion(\"1.0.0\"),\n\t\tserver.WithRetries(2),\n\t\tserver.WithLoadBalance(\"roundrobin\"),\n\t}, opts...)",
+	} {
+		if !strings.Contains(stubs, want) {
+			t.Errorf("stubs lack %q", want)
+		}
+	}
+	// ClientInfo, ServiceInfo and the MethodInfo of Greet publish the defaults.
+	meta := strings.Join(strings.Fields(stubs), " ")
+	for _, want := range []struct {
+		meta string
+		n    int
+	}{
+		{`"group": "greet", "version": "1.0.0", "timeout": 3 * time.Second, "retries": 2, "loadbalance": "roundrobin", },`, 2},
+		{`"timeout": 1500 * time.Millisecond, "retries": 0, },`, 1},
+	} {
+		if got := strings.Count(meta, want.meta); got != want.n {
+			t.Errorf("stubs declare %q %d times, want %d", want.meta, got, want.n)
+		}
+	}
+	start := strings.Index(stubs, "func (c *GreetServiceImpl) Plain(")
+	if start < 0 {
+		t.Fatal("stubs lack the Plain client method")
+	}
+	if plain := stubs[start:]; strings.Contains(plain[:strings.Index(plain, "\n}\n")], "CallOption{") {
+		t.Errorf("Plain has call defaults:\n%s", plain)
+	}
+}
+
+func TestDubboOptionsTimeout(t *testing.T) {
+	for _, timeout := range []string{"soon", "0s", "-1s"} {
+		t.Run(timeout, func(t *testing.T) {
+			setParams(t)
+			plugin := newPlugin(t, newRequest(t, strings.Replace(dubboOptionsProto, `"1500ms"`, `"`+timeout+`"`, 1)))
+			_, err := ProcessProtoFile(plugin, plugin.FilesByPath["greet.proto"])
+			want := `dubbo option of greet.GreetService.Greet: timeout "` + timeout + `" is not a positive duration`
+			if err == nil || err.Error() != want {
+				t.Errorf("ProcessProtoFile() = %v, want %s", err, want)
+			}
+		})
+	}
+}
+
+func TestDubboOptionsExtensionNumber(t *testing.T) {
+	// Files using the options carry the number on the wire, so it must not change
+	// until one is registered for Apache Dubbo.
+	if n := dubbo.E_Service.TypeDescriptor().Number(); n != 56001 {
+		t.Errorf("dubbo.service has number %d, want 56001", n)
+	}
+	if n := dubbo.E_Method.TypeDescriptor().Number(); n != 56001 {
+		t.Errorf("dubbo.method has number %d, want 56001", n)
+	}
+}
@@ -0,0 +1,798 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	base64Package    = protogen.GoImportPath("encoding/base64")
+	jsonPackage      = protogen.GoImportPath("encoding/json")
+	fmtPackage       = protogen.GoImportPath("fmt")
+	urlPackage       = protogen.GoImportPath("net/url")
+	stringsPackage   = protogen.GoImportPath("strings")
+	strconvPackage   = protogen.GoImportPath("strconv")
+	protoPackage     = protogen.GoImportPath("google.golang.org/protobuf/proto")
+	protojsonPackage = protogen.GoImportPath("google.golang.org/protobuf/encoding/protojson")
+)
+
+// httpRuleField is the field number of the google.api.http extension of
+// google.protobuf.MethodOptions.
+const httpRuleField = 72295728
+
+// Field numbers of google.api.HttpRule and google.api.CustomHttpPattern.
+const (
+	httpRuleGet                = 2
+	httpRulePut                = 3
+	httpRulePost               = 4
+	httpRuleDelete             = 5
+	httpRulePatch              = 6
+	httpRuleBody               = 7
+	httpRuleCustom             = 8
+	httpRuleAdditionalBindings = 11
+	httpRuleResponseBody       = 12
+	customHTTPPatternKind      = 1
+	customHTTPPatternPath      = 2
+)
+
+// HTTPRule is a REST binding of a method, read from its google.api.http option.
+type HTTPRule struct {
+	// Method is the HTTP method and Pattern the path template of the binding.
+	Method  string
+	Pattern string
+	// Body and ResponseBody select the request and response field mapped to the
+	// HTTP body: empty for none, "*" for the whole message, or a field name.
+	Body         string
+	ResponseBody string
+	// Segments is Pattern split at "/", with variables replaced by their
+	// segments. Verb is the custom verb after the last ":".
+	Segments []string
+	Verb     string
+	Vars     []PathVar
+}
+
+// PathVar binds Segments[Start:End] of an HTTPRule to a request field. End is
+// -1 when the variable extends to the end of the path.
+type PathVar struct {
+	FieldPath string
+	Start     int
+	End       int
+}
+
+// httpRules reads the google.api.http bindings of method. The extension is not
+// linked into the plugin, so it is decoded from the unknown fields of the
+// method options.
+func httpRules(method *protogen.Method) ([]HTTPRule, error) {
+	opts, ok := method.Desc.Options().(*descriptorpb.MethodOptions)
+	if !ok || opts == nil {
+		return nil, nil
+	}
+	var rules []HTTPRule
+	err := rangeFields(opts.ProtoReflect().GetUnknown(), func(num protowire.Number, value []byte) error {
+		if num != httpRuleField {
+			return nil
+		}
+		parsed, err := parseHTTPRule(value, true)
+		if err != nil {
+			return err
+		}
+		for _, rule := range parsed {
+			if err := checkHTTPRule(method, &rule); err != nil {
+				return fmt.Errorf("google.api.http option of %s: %w", method.Desc.FullName(), err)
+			}
+			rules = append(rules, rule)
+		}
+		return nil
+	})
+	return rules, err
+}
+
+// rangeFields calls f with the length-delimited fields of the wire format
+// message b.
+func rangeFields(b []byte, f func(num protowire.Number, value []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ == protowire.BytesType {
+			value, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			if err := f(num, value); err != nil {
+				return err
+			}
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// parseHTTPRule decodes a google.api.HttpRule. The additional bindings of the
+// top-level rule follow it in the result; they cannot nest any further.
+func parseHTTPRule(b []byte, top bool) ([]HTTPRule, error) {
+	rule := HTTPRule{}
+	var additional [][]byte
+	err := rangeFields(b, func(num protowire.Number, value []byte) error {
+		switch num {
+		case httpRuleGet, httpRulePut, httpRulePost, httpRuleDelete, httpRulePatch:
+			rule.Method = map[protowire.Number]string{
+				httpRuleGet:    "GET",
+				httpRulePut:    "PUT",
+				httpRulePost:   "POST",
+				httpRuleDelete: "DELETE",
+				httpRulePatch:  "PATCH",
+			}[num]
+			rule.Pattern = string(value)
+		case httpRuleCustom:
+			return rangeFields(value, func(num protowire.Number, value []byte) error {
+				switch num {
+				case customHTTPPatternKind:
+					rule.Method = string(value)
+				case customHTTPPatternPath:
+					rule.Pattern = string(value)
+				}
+				return nil
+			})
+		case httpRuleBody:
+			rule.Body = string(value)
+		case httpRuleResponseBody:
+			rule.ResponseBody = string(value)
+		case httpRuleAdditionalBindings:
+			if top {
+				additional = append(additional, value)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	rules := []HTTPRule{rule}
+	for _, b := range additional {
+		more, err := parseHTTPRule(b, false)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, more...)
+	}
+	return rules, nil
+}
+
+// checkHTTPRule parses the path template of rule and checks that the fields it
+// refers to exist on the messages of method.
+func checkHTTPRule(method *protogen.Method, rule *HTTPRule) error {
+	if rule.Method == "" || rule.Pattern == "" {
+		return errors.New("binding without HTTP method or path")
+	}
+	if err := parsePathTemplate(rule); err != nil {
+		return fmt.Errorf("path %q: %w", rule.Pattern, err)
+	}
+	for _, v := range rule.Vars {
+		field, err := lookupFieldPath(method.Input, v.FieldPath)
+		if err != nil {
+			return err
+		}
+		if field.Message != nil || field.Desc.IsList() {
+			return fmt.Errorf("path variable %s must be a singular scalar field", v.FieldPath)
+		}
+	}
+	if err := checkBodyField(method.Input, rule.Body, "body"); err != nil {
+		return err
+	}
+	return checkBodyField(method.Output, rule.ResponseBody, "response_body")
+}
+
+func checkBodyField(message *protogen.Message, name, option string) error {
+	if name == "" || name == "*" {
+		return nil
+	}
+	for _, field := range message.Fields {
+		if string(field.Desc.Name()) == name {
+			if field.Message == nil || field.Desc.IsList() || field.Desc.IsMap() {
+				return fmt.Errorf("%s %s must be a singular message field", option, name)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%s %s is not a field of %s", option, name, message.Desc.FullName())
+}
+
+// lookupFieldPath resolves a dotted field path like "book.name" in message.
+func lookupFieldPath(message *protogen.Message, path string) (*protogen.Field, error) {
+	var field *protogen.Field
+	for i, name := range strings.Split(path, ".") {
+		if i > 0 {
+			if field.Message == nil || field.Desc.IsList() {
+				return nil, fmt.Errorf("field path %s crosses non-message field %s", path, field.Desc.Name())
+			}
+			message = field.Message
+		}
+		field = nil
+		for _, f := range message.Fields {
+			if string(f.Desc.Name()) == name {
+				field = f
+			}
+		}
+		if field == nil {
+			return nil, fmt.Errorf("field path %s: %s is not a field of %s", path, name, message.Desc.FullName())
+		}
+	}
+	return field, nil
+}
+
+// parsePathTemplate splits rule.Pattern into segments, a verb and variables,
+// following the template syntax documented in google/api/http.proto:
+//
+//	Template = "/" Segments [ Verb ] ;
+//	Segments = Segment { "/" Segment } ;
+//	Segment  = "*" | "**" | LITERAL | Variable ;
+//	Variable = "{" FieldPath [ "=" Segments ] "}" ;
+//	Verb     = ":" LITERAL ;
+func parsePathTemplate(rule *HTTPRule) error {
+	if !strings.HasPrefix(rule.Pattern, "/") {
+		return errors.New("must start with /")
+	}
+	path := rule.Pattern[1:]
+	if i := strings.LastIndex(path, ":"); i > strings.LastIndexAny(path, "/}") {
+		rule.Verb = path[i+1:]
+		path = path[:i]
+		if rule.Verb == "" {
+			return errors.New("empty verb")
+		}
+	}
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range path {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '/':
+			if depth == 0 {
+				parts = append(parts, path[start:i])
+				start = i + 1
+			}
+		}
+		if depth < 0 || depth > 1 {
+			return errors.New("unbalanced braces")
+		}
+	}
+	if depth != 0 {
+		return errors.New("unbalanced braces")
+	}
+	parts = append(parts, path[start:])
+
+	for _, part := range parts {
+		if !strings.HasPrefix(part, "{") {
+			if strings.ContainsAny(part, "{}") {
+				return fmt.Errorf("variable in %s must be a whole segment", part)
+			}
+			if err := addSegment(rule, part); err != nil {
+				return err
+			}
+			continue
+		}
+		if !strings.HasSuffix(part, "}") {
+			return fmt.Errorf("variable %s must be a whole segment", part)
+		}
+		fieldPath, segments, ok := strings.Cut(part[1:len(part)-1], "=")
+		if !ok {
+			segments = "*"
+		}
+		for _, name := range strings.Split(fieldPath, ".") {
+			if name == "" {
+				return fmt.Errorf("variable %s has an invalid field path", part)
+			}
+		}
+		v := PathVar{FieldPath: fieldPath, Start: len(rule.Segments)}
+		for _, segment := range strings.Split(segments, "/") {
+			if strings.ContainsAny(segment, "{}") {
+				return fmt.Errorf("variable %s cannot nest variables", fieldPath)
+			}
+			if err := addSegment(rule, segment); err != nil {
+				return err
+			}
+		}
+		v.End = len(rule.Segments)
+		if rule.Segments[len(rule.Segments)-1] == "**" {
+			v.End = -1
+		}
+		rule.Vars = append(rule.Vars, v)
+	}
+	return nil
+}
+
+func addSegment(rule *HTTPRule, segment string) error {
+	if segment == "" {
+		return errors.New("empty segment")
+	}
+	if len(rule.Segments) > 0 && rule.Segments[len(rule.Segments)-1] == "**" {
+		return errors.New("** must be the last segment")
+	}
+	rule.Segments = append(rule.Segments, segment)
+	return nil
+}
+
+// hasHTTPRules reports whether a unary method of s has REST bindings. Streaming
+// methods are not transcoded.
+func hasHTTPRules(s Service) bool {
+	for _, m := range s.Methods {
+		if !m.isStream() && len(m.HTTPRules) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// genREST writes the REST transcoding of the services with google.api.http
+// bindings: a route table, an http.Handler serving it and the helpers mapping
+// path variables, query parameters and bodies onto the request message.
+func genREST(g *protogen.GeneratedFile, t TripleGo) {
+	for _, s := range t.Services {
+		if hasHTTPRules(s) {
+			genRESTRoutes(g, s)
+			genRESTHelpers(g, s)
+		}
+	}
+}
+
+func genRESTRoutes(g *protogen.GeneratedFile, s Service) {
+	route := s.GoName + "RESTRoute"
+	v := restPrefix(s) + "Var"
+	protoMessage := protoPackage.Ident("Message")
+	ctx := contextPackage.Ident("Context")
+	g.P("// ", route, " is a REST route of the ", s.FullName, " service, declared with a")
+	g.P("// google.api.http option and mapped onto a unary method of ", s.GoName, "Handler.")
+	g.P("type ", route, " struct {")
+	g.P("// Method is the HTTP method and Pattern the path template of the route.")
+	g.P("Method string")
+	g.P("Pattern string")
+	g.P("// Procedure is the triple procedure the route is mapped onto.")
+	g.P("Procedure string")
+	g.P("// Handler serves requests to the route. Requests to other paths get a 404.")
+	g.P("Handler ", httpPackage.Ident("Handler"))
+	g.P()
+	g.P("segments []string")
+	g.P("verb string")
+	g.P("vars []", v)
+	g.P("body string")
+	g.P("responseBody string")
+	g.P("newRequest func() ", protoMessage)
+	g.P("call func(", ctx, ", ", protoMessage, ") (", protoMessage, ", error)")
+	g.P("}")
+	g.P()
+	g.P("type ", v, " struct {")
+	g.P("field string")
+	g.P("start, end int")
+	g.P("}")
+	g.P()
+	g.P("// New", s.GoName, "RESTRoutes returns the REST routes of the ", s.FullName, " service, served")
+	g.P("// by hdlr. The routes can be mounted one by one, or all at once through")
+	g.P("// New", s.GoName, "RESTHandler.")
+	g.P("func New", s.GoName, "RESTRoutes(hdlr ", s.GoName, "Handler) []", route, " {")
+	g.P("routes := []", route, "{")
+	for _, m := range s.Methods {
+		if m.isStream() {
+			continue
+		}
+		for _, rule := range m.HTTPRules {
+			g.P("{")
+			g.P("Method: ", strconv.Quote(rule.Method), ",")
+			g.P("Pattern: ", strconv.Quote(rule.Pattern), ",")
+			g.P("Procedure: ", s.GoName, m.GoName, "Procedure,")
+			segments := make([]string, 0, len(rule.Segments))
+			for _, segment := range rule.Segments {
+				segments = append(segments, strconv.Quote(segment))
+			}
+			g.P("segments: []string{", strings.Join(segments, ", "), "},")
+			if rule.Verb != "" {
+				g.P("verb: ", strconv.Quote(rule.Verb), ",")
+			}
+			if len(rule.Vars) > 0 {
+				g.P("vars: []", v, "{")
+				for _, pv := range rule.Vars {
+					g.P("{", strconv.Quote(pv.FieldPath), ", ", pv.Start, ", ", pv.End, "},")
+				}
+				g.P("},")
+			}
+			if rule.Body != "" {
+				g.P("body: ", strconv.Quote(rule.Body), ",")
+			}
+			if rule.ResponseBody != "" {
+				g.P("responseBody: ", strconv.Quote(rule.ResponseBody), ",")
+			}
+			g.P("newRequest: func() ", protoMessage, " {")
+			g.P("return new(", m.RequestType, ")")
+			g.P("},")
+			g.P("call: func(ctx ", ctx, ", req ", protoMessage, ") (", protoMessage, ", error) {")
+			if validateHandler() && m.validates() {
+				genHandlerValidateCall(g, s, "hdlr", "req")
+			}
+			g.P("res, err := hdlr.", m.GoName, "(ctx, req.(*", m.RequestType, "))")
+			g.P("if err != nil {")
+			g.P("return nil, err")
+			g.P("}")
+			g.P("return res, nil")
+			g.P("},")
+			g.P("},")
+		}
+	}
+	g.P("}")
+	g.P("for i := range routes {")
+	g.P("route := &routes[i]")
+	g.P("route.Handler = ", httpPackage.Ident("HandlerFunc"), "(func(w ", httpPackage.Ident("ResponseWriter"), ", r *", httpPackage.Ident("Request"), ") {")
+	g.P("vars, ok := route.match(r.URL.EscapedPath())")
+	g.P("if !ok || r.Method != route.Method {")
+	g.P(httpPackage.Ident("NotFound"), "(w, r)")
+	g.P("return")
+	g.P("}")
+	g.P("route.serve(w, r, vars)")
+	g.P("})")
+	g.P("}")
+	g.P("return routes")
+	g.P("}")
+	g.P()
+	g.P("// New", s.GoName, "RESTHandler returns an http.Handler serving all REST routes of the")
+	g.P("// ", s.FullName, " service with hdlr. It can be mounted on an http.ServeMux.")
+	g.P("func New", s.GoName, "RESTHandler(hdlr ", s.GoName, "Handler) ", httpPackage.Ident("Handler"), " {")
+	g.P("routes := New", s.GoName, "RESTRoutes(hdlr)")
+	g.P("return ", httpPackage.Ident("HandlerFunc"), "(func(w ", httpPackage.Ident("ResponseWriter"), ", r *", httpPackage.Ident("Request"), ") {")
+	g.P("matched := false")
+	g.P("for i := range routes {")
+	g.P("vars, ok := routes[i].match(r.URL.EscapedPath())")
+	g.P("if !ok {")
+	g.P("continue")
+	g.P("}")
+	g.P("if r.Method != routes[i].Method {")
+	g.P("matched = true")
+	g.P("continue")
+	g.P("}")
+	g.P("routes[i].serve(w, r, vars)")
+	g.P("return")
+	g.P("}")
+	g.P("if matched {")
+	g.P(httpPackage.Ident("Error"), "(w, ", httpPackage.Ident("StatusText"), "(", httpPackage.Ident("StatusMethodNotAllowed"), "), ", httpPackage.Ident("StatusMethodNotAllowed"), ")")
+	g.P("return")
+	g.P("}")
+	g.P(httpPackage.Ident("NotFound"), "(w, r)")
+	g.P("})")
+	g.P("}")
+	g.P()
+	g.P("// match matches the escaped path against the template of the route and returns")
+	g.P("// the values of its variables. Values of a single segment are unescaped. Values")
+	g.P("// of several segments keep %2F escaped, so that it can be told apart from the")
+	g.P("// \"/\" between the segments, as google.api.http specifies.")
+	g.P("func (route *", route, ") match(path string) ([]string, bool) {")
+	g.P("if route.verb != \"\" {")
+	g.P("if !", stringsPackage.Ident("HasSuffix"), "(path, \":\"+route.verb) {")
+	g.P("return nil, false")
+	g.P("}")
+	g.P("path = ", stringsPackage.Ident("TrimSuffix"), "(path, \":\"+route.verb)")
+	g.P("}")
+	g.P("escaped := ", stringsPackage.Ident("Split"), "(", stringsPackage.Ident("TrimPrefix"), "(path, \"/\"), \"/\")")
+	g.P("parts := make([]string, len(escaped))")
+	g.P("for i, part := range escaped {")
+	g.P("unescaped, err := ", urlPackage.Ident("PathUnescape"), "(part)")
+	g.P("if err != nil {")
+	g.P("return nil, false")
+	g.P("}")
+	g.P("parts[i] = unescaped")
+	g.P("}")
+	g.P("n := len(route.segments)")
+	g.P("if route.segments[n-1] == \"**\" {")
+	g.P("if len(parts) < n-1 {")
+	g.P("return nil, false")
+	g.P("}")
+	g.P("} else if len(parts) != n {")
+	g.P("return nil, false")
+	g.P("}")
+	g.P("for i, segment := range route.segments {")
+	g.P("switch segment {")
+	g.P("case \"**\":")
+	g.P("case \"*\":")
+	g.P("if parts[i] == \"\" {")
+	g.P("return nil, false")
+	g.P("}")
+	g.P("default:")
+	g.P("if parts[i] != segment {")
+	g.P("return nil, false")
+	g.P("}")
+	g.P("}")
+	g.P("}")
+	g.P("vars := make([]string, len(route.vars))")
+	g.P("for i, v := range route.vars {")
+	g.P("if v.end == v.start+1 {")
+	g.P("vars[i] = parts[v.start]")
+	g.P("continue")
+	g.P("}")
+	g.P("end := v.end")
+	g.P("if end < 0 {")
+	g.P("end = len(parts)")
+	g.P("}")
+	g.P("values := make([]string, 0, end-v.start)")
+	g.P("for _, part := range escaped[v.start:end] {")
+	g.P("// Every % starts an escape in a path that unescapes, so this only splits")
+	g.P("// at escaped slashes.")
+	g.P("pieces := ", stringsPackage.Ident("Split"), "(", stringsPackage.Ident("ReplaceAll"), "(part, \"%2f\", \"%2F\"), \"%2F\")")
+	g.P("for j, piece := range pieces {")
+	g.P("pieces[j], _ = ", urlPackage.Ident("PathUnescape"), "(piece)")
+	g.P("}")
+	g.P("values = append(values, ", stringsPackage.Ident("Join"), "(pieces, \"%2F\"))")
+	g.P("}")
+	g.P("vars[i] = ", stringsPackage.Ident("Join"), "(values, \"/\")")
+	g.P("}")
+	g.P("return vars, true")
+	g.P("}")
+	g.P()
+	g.P("// bound reports whether the query parameter key names a field that is already")
+	g.P("// set from the path or the body.")
+	g.P("func (route *", route, ") bound(key string) bool {")
+	g.P("fields := []string{route.body}")
+	g.P("for _, v := range route.vars {")
+	g.P("fields = append(fields, v.field)")
+	g.P("}")
+	g.P("for _, field := range fields {")
+	g.P("if field != \"\" && (key == field || ", stringsPackage.Ident("HasPrefix"), "(key, field+\".\")) {")
+	g.P("return true")
+	g.P("}")
+	g.P("}")
+	g.P("return false")
+	g.P("}")
+	g.P()
+	invalid := restPrefix(s) + "InvalidArgument"
+	writeError := restPrefix(s) + "WriteError"
+	setField := restPrefix(s) + "SetField"
+	g.P("func (route *", route, ") serve(w ", httpPackage.Ident("ResponseWriter"), ", r *", httpPackage.Ident("Request"), ", vars []string) {")
+	g.P("req := route.newRequest()")
+	g.P("msg := req.ProtoReflect()")
+	g.P("if route.body != \"\" {")
+	g.P("body, err := ", ioPackage.Ident("ReadAll"), "(r.Body)")
+	g.P("if err != nil {")
+	g.P(writeError, "(w, ", invalid, "(err))")
+	g.P("return")
+	g.P("}")
+	g.P("target := req")
+	g.P("if route.body != \"*\" {")
+	g.P("field := msg.Descriptor().Fields().ByName(", protoreflectPackage.Ident("Name"), "(route.body))")
+	g.P("target = msg.Mutable(field).Message().Interface()")
+	g.P("}")
+	g.P("if len(body) > 0 {")
+	g.P("if err := ", protojsonPackage.Ident("Unmarshal"), "(body, target); err != nil {")
+	g.P(writeError, "(w, ", invalid, "(err))")
+	g.P("return")
+	g.P("}")
+	g.P("}")
+	g.P("}")
+	g.P("for i, v := range route.vars {")
+	g.P("if err := ", setField, "(msg, v.field, vars[i]); err != nil {")
+	g.P(writeError, "(w, ", invalid, "(err))")
+	g.P("return")
+	g.P("}")
+	g.P("}")
+	g.P("if route.body != \"*\" {")
+	g.P("for key, values := range r.URL.Query() {")
+	g.P("if route.bound(key) {")
+	g.P("continue")
+	g.P("}")
+	g.P("for _, value := range values {")
+	g.P("if err := ", setField, "(msg, key, value); err != nil {")
+	g.P(writeError, "(w, ", invalid, "(err))")
+	g.P("return")
+	g.P("}")
+	g.P("}")
+	g.P("}")
+	g.P("}")
+	g.P("res, err := route.call(r.Context(), req)")
+	g.P("if err != nil {")
+	g.P(writeError, "(w, err)")
+	g.P("return")
+	g.P("}")
+	g.P("if route.responseBody != \"\" {")
+	g.P("out := res.ProtoReflect()")
+	g.P("res = out.Get(out.Descriptor().Fields().ByName(", protoreflectPackage.Ident("Name"), "(route.responseBody))).Message().Interface()")
+	g.P("}")
+	g.P("data, err := ", protojsonPackage.Ident("Marshal"), "(res)")
+	g.P("if err != nil {")
+	g.P(writeError, "(w, err)")
+	g.P("return")
+	g.P("}")
+	g.P("w.Header().Set(\"Content-Type\", \"application/json\")")
+	g.P("_, _ = w.Write(data)")
+	g.P("}")
+	g.P()
+}
+
+func genRESTHelpers(g *protogen.GeneratedFile, s Service) {
+	prefix := restPrefix(s)
+	pr := func(name string) protogen.GoIdent { return protoreflectPackage.Ident(name) }
+	code := func(name string) protogen.GoIdent { return tripleProtocolPackage.Ident(name) }
+	g.P("func ", prefix, "InvalidArgument(err error) error {")
+	g.P("return ", code("NewError"), "(", code("CodeInvalidArgument"), ", err)")
+	g.P("}")
+	g.P()
+	g.P("// ", prefix, "SetField sets the field at the dotted path in msg from its string form, as")
+	g.P("// found in path variables and query parameters. Repeated fields are appended to.")
+	g.P("func ", prefix, "SetField(msg ", pr("Message"), ", path, value string) error {")
+	g.P("names := ", stringsPackage.Ident("Split"), "(path, \".\")")
+	g.P("for i, name := range names {")
+	g.P("fields := msg.Descriptor().Fields()")
+	g.P("field := fields.ByName(", pr("Name"), "(name))")
+	g.P("if field == nil {")
+	g.P("field = fields.ByJSONName(name)")
+	g.P("}")
+	g.P("if field == nil {")
+	g.P("return ", fmtPackage.Ident("Errorf"), "(\"unknown field %q in %s\", path, msg.Descriptor().FullName())")
+	g.P("}")
+	g.P("if i < len(names)-1 {")
+	g.P("if field.Message() == nil || field.IsList() || field.IsMap() {")
+	g.P("return ", fmtPackage.Ident("Errorf"), "(\"field %q of %s is not a message\", name, msg.Descriptor().FullName())")
+	g.P("}")
+	g.P("msg = msg.Mutable(field).Message()")
+	g.P("continue")
+	g.P("}")
+	g.P("if field.Message() != nil || field.IsMap() {")
+	g.P("return ", fmtPackage.Ident("Errorf"), "(\"field %q cannot be set from a string\", path)")
+	g.P("}")
+	g.P("v, err := ", prefix, "ParseScalar(field, value)")
+	g.P("if err != nil {")
+	g.P("return err")
+	g.P("}")
+	g.P("if field.IsList() {")
+	g.P("msg.Mutable(field).List().Append(v)")
+	g.P("} else {")
+	g.P("msg.Set(field, v)")
+	g.P("}")
+	g.P("}")
+	g.P("return nil")
+	g.P("}")
+	g.P()
+	g.P("func ", prefix, "ParseScalar(field ", pr("FieldDescriptor"), ", value string) (", pr("Value"), ", error) {")
+	g.P("var v ", pr("Value"))
+	g.P("var err error")
+	g.P("switch field.Kind() {")
+	g.P("case ", pr("StringKind"), ":")
+	g.P("v = ", pr("ValueOfString"), "(value)")
+	g.P("case ", pr("BoolKind"), ":")
+	g.P("var b bool")
+	g.P("b, err = ", strconvPackage.Ident("ParseBool"), "(value)")
+	g.P("v = ", pr("ValueOfBool"), "(b)")
+	g.P("case ", pr("EnumKind"), ":")
+	g.P("if ev := field.Enum().Values().ByName(", pr("Name"), "(value)); ev != nil {")
+	g.P("return ", pr("ValueOfEnum"), "(ev.Number()), nil")
+	g.P("}")
+	g.P("var n int64")
+	g.P("n, err = ", strconvPackage.Ident("ParseInt"), "(value, 10, 32)")
+	g.P("v = ", pr("ValueOfEnum"), "(", pr("EnumNumber"), "(n))")
+	g.P("case ", pr("Int32Kind"), ", ", pr("Sint32Kind"), ", ", pr("Sfixed32Kind"), ":")
+	g.P("var n int64")
+	g.P("n, err = ", strconvPackage.Ident("ParseInt"), "(value, 10, 32)")
+	g.P("v = ", pr("ValueOfInt32"), "(int32(n))")
+	g.P("case ", pr("Int64Kind"), ", ", pr("Sint64Kind"), ", ", pr("Sfixed64Kind"), ":")
+	g.P("var n int64")
+	g.P("n, err = ", strconvPackage.Ident("ParseInt"), "(value, 10, 64)")
+	g.P("v = ", pr("ValueOfInt64"), "(n)")
+	g.P("case ", pr("Uint32Kind"), ", ", pr("Fixed32Kind"), ":")
+	g.P("var n uint64")
+	g.P("n, err = ", strconvPackage.Ident("ParseUint"), "(value, 10, 32)")
+	g.P("v = ", pr("ValueOfUint32"), "(uint32(n))")
+	g.P("case ", pr("Uint64Kind"), ", ", pr("Fixed64Kind"), ":")
+	g.P("var n uint64")
+	g.P("n, err = ", strconvPackage.Ident("ParseUint"), "(value, 10, 64)")
+	g.P("v = ", pr("ValueOfUint64"), "(n)")
+	g.P("case ", pr("FloatKind"), ":")
+	g.P("var f float64")
+	g.P("f, err = ", strconvPackage.Ident("ParseFloat"), "(value, 32)")
+	g.P("v = ", pr("ValueOfFloat32"), "(float32(f))")
+	g.P("case ", pr("DoubleKind"), ":")
+	g.P("var f float64")
+	g.P("f, err = ", strconvPackage.Ident("ParseFloat"), "(value, 64)")
+	g.P("v = ", pr("ValueOfFloat64"), "(f)")
+	g.P("case ", pr("BytesKind"), ":")
+	g.P("var b []byte")
+	g.P("b, err = ", base64Package.Ident("StdEncoding"), ".DecodeString(value)")
+	g.P("if err != nil {")
+	g.P("b, err = ", base64Package.Ident("URLEncoding"), ".DecodeString(value)")
+	g.P("}")
+	g.P("v = ", pr("ValueOfBytes"), "(b)")
+	g.P("default:")
+	g.P("err = ", errorsPackage.Ident("New"), "(\"unsupported field kind \" + field.Kind().String())")
+	g.P("}")
+	g.P("if err != nil {")
+	g.P("return ", pr("Value"), "{}, ", fmtPackage.Ident("Errorf"), "(\"invalid value %q for field %s: %w\", value, field.FullName(), err)")
+	g.P("}")
+	g.P("return v, nil")
+	g.P("}")
+	g.P()
+	g.P("// ", prefix, "WriteError writes err as a JSON error with the HTTP status matching its")
+	g.P("// triple error code.")
+	g.P("func ", prefix, "WriteError(w ", httpPackage.Ident("ResponseWriter"), ", err error) {")
+	g.P("code := ", code("CodeOf"), "(err)")
+	g.P("data, _ := ", jsonPackage.Ident("Marshal"), "(map[string]string{\"code\": code.String(), \"message\": err.Error()})")
+	g.P("status := ", httpPackage.Ident("StatusInternalServerError"))
+	g.P("switch code {")
+	for _, c := range []struct{ code, status string }{
+		{"CodeCanceled", "499"},
+		{"CodeInvalidArgument", "StatusBadRequest"},
+		{"CodeDeadlineExceeded", "StatusGatewayTimeout"},
+		{"CodeNotFound", "StatusNotFound"},
+		{"CodeAlreadyExists", "StatusConflict"},
+		{"CodePermissionDenied", "StatusForbidden"},
+		{"CodeResourceExhausted", "StatusTooManyRequests"},
+		{"CodeFailedPrecondition", "StatusBadRequest"},
+		{"CodeAborted", "StatusConflict"},
+		{"CodeOutOfRange", "StatusBadRequest"},
+		{"CodeUnimplemented", "StatusNotImplemented"},
+		{"CodeUnavailable", "StatusServiceUnavailable"},
+		{"CodeUnauthenticated", "StatusUnauthorized"},
+	} {
+		g.P("case ", code(c.code), ":")
+		if c.status == "499" {
+			// Client Closed Request, as used by nginx and grpc-gateway.
+			g.P("status = 499")
+		} else {
+			g.P("status = ", httpPackage.Ident(c.status))
+		}
+	}
+	g.P("}")
+	g.P("w.Header().Set(\"Content-Type\", \"application/json\")")
+	g.P("w.WriteHeader(status)")
+	g.P("_, _ = w.Write(data)")
+	g.P("}")
+	g.P()
+}
+
+// restPrefix prefixes the unexported REST helpers of s.
+func restPrefix(s Service) string {
+	return unexported(s.GoName + "REST")
+}
+
+// restIdents lists the package-level identifiers genREST declares for s.
+func restIdents(s Service) []string {
+	if !hasHTTPRules(s) {
+		return nil
+	}
+	prefix := restPrefix(s)
+	return []string{
+		s.GoName + "RESTRoute",
+		"New" + s.GoName + "RESTRoutes",
+		"New" + s.GoName + "RESTHandler",
+		prefix + "Var",
+		prefix + "InvalidArgument",
+		prefix + "SetField",
+		prefix + "ParseScalar",
+		prefix + "WriteError",
+	}
+}
@@ -0,0 +1,332 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestParsePathTemplate(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		segments []string
+		verb     string
+		vars     []PathVar
+		// err is a substring of the error, which is nil when empty.
+		err string
+	}{
+		{
+			pattern:  "/v1/books",
+			segments: []string{"v1", "books"},
+		},
+		{
+			pattern:  "/v1/books/{name}",
+			segments: []string{"v1", "books", "*"},
+			vars:     []PathVar{{FieldPath: "name", Start: 2, End: 3}},
+		},
+		{
+			pattern:  "/v1/{name=shelves/*/books/*}",
+			segments: []string{"v1", "shelves", "*", "books", "*"},
+			vars:     []PathVar{{FieldPath: "name", Start: 1, End: 5}},
+		},
+		{
+			pattern:  "/v1/{shelf}/books/{book}",
+			segments: []string{"v1", "*", "books", "*"},
+			vars: []PathVar{
+				{FieldPath: "shelf", Start: 1, End: 2},
+				{FieldPath: "book", Start: 3, End: 4},
+			},
+		},
+		{
+			pattern:  "/v1/files/{path=**}",
+			segments: []string{"v1", "files", "**"},
+			vars:     []PathVar{{FieldPath: "path", Start: 2, End: -1}},
+		},
+		{
+			pattern:  "/v1/{name=files/**}",
+			segments: []string{"v1", "files", "**"},
+			vars:     []PathVar{{FieldPath: "name", Start: 1, End: -1}},
+		},
+		{
+			pattern:  "/v1/*/books/**",
+			segments: []string{"v1", "*", "books", "**"},
+		},
+		{
+			pattern:  "/v1/{book.name=books/*}",
+			segments: []string{"v1", "books", "*"},
+			vars:     []PathVar{{FieldPath: "book.name", Start: 1, End: 3}},
+		},
+		{
+			pattern:  "/v1/{book.author.id}/books",
+			segments: []string{"v1", "*", "books"},
+			vars:     []PathVar{{FieldPath: "book.author.id", Start: 1, End: 2}},
+		},
+		{
+			pattern:  "/v1/books:batchGet",
+			segments: []string{"v1", "books"},
+			verb:     "batchGet",
+		},
+		{
+			pattern:  "/v1/{name=books/*}:publish",
+			segments: []string{"v1", "books", "*"},
+			verb:     "publish",
+			vars:     []PathVar{{FieldPath: "name", Start: 1, End: 3}},
+		},
+		{
+			pattern:  "/v1/{path=**}:download",
+			segments: []string{"v1", "**"},
+			verb:     "download",
+			vars:     []PathVar{{FieldPath: "path", Start: 1, End: -1}},
+		},
+		{
+			// A colon before the last segment is part of a literal.
+			pattern:  "/v1/a:b/books",
+			segments: []string{"v1", "a:b", "books"},
+		},
+		{pattern: "v1/books", err: "must start with /"},
+		{pattern: "/", err: "empty segment"},
+		{pattern: "/v1//books", err: "empty segment"},
+		{pattern: "/v1/books/", err: "empty segment"},
+		{pattern: "/v1/books:", err: "empty verb"},
+		{pattern: "/v1/{name", err: "unbalanced braces"},
+		{pattern: "/v1/name}", err: "unbalanced braces"},
+		{pattern: "/v1/{a={b}}", err: "unbalanced braces"},
+		{pattern: "/v1/{name}x", err: "must be a whole segment"},
+		{pattern: "/v1/x{name}", err: "must be a whole segment"},
+		{pattern: "/v1/{}", err: "invalid field path"},
+		{pattern: "/v1/{=books/*}", err: "invalid field path"},
+		{pattern: "/v1/{book..name}", err: "invalid field path"},
+		{pattern: "/v1/{name=}", err: "empty segment"},
+		{pattern: "/v1/**/books", err: "** must be the last segment"},
+		{pattern: "/v1/{path=**}/books", err: "** must be the last segment"},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			rule := HTTPRule{Method: "GET", Pattern: test.pattern}
+			err := parsePathTemplate(&rule)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(rule.Segments, test.segments) {
+				t.Errorf("segments %q, want %q", rule.Segments, test.segments)
+			}
+			if rule.Verb != test.verb {
+				t.Errorf("verb %q, want %q", rule.Verb, test.verb)
+			}
+			if !reflect.DeepEqual(rule.Vars, test.vars) {
+				t.Errorf("vars %+v, want %+v", rule.Vars, test.vars)
+			}
+		})
+	}
+}
+
+// httpRule encodes a google.api.HttpRule with the given fields, which are the
+// field numbers of strings or, for additional bindings, encoded rules.
+func httpRule(fields ...any) []byte {
+	var b []byte
+	for i := 0; i < len(fields); i += 2 {
+		num := protowire.Number(fields[i].(int))
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		switch value := fields[i+1].(type) {
+		case string:
+			b = protowire.AppendString(b, value)
+		case []byte:
+			b = protowire.AppendBytes(b, value)
+		}
+	}
+	return b
+}
+
+const bookMessages = `
+message_type {
+	name: "Book"
+	field { name: "name" number: 1 type: TYPE_STRING json_name: "name" }
+	field { name: "tags" number: 2 label: LABEL_REPEATED type: TYPE_STRING json_name: "tags" }
+}
+message_type {
+	name: "UpdateBookRequest"
+	field { name: "book" number: 1 type: TYPE_MESSAGE type_name: ".greet.Book" json_name: "book" }
+	field { name: "books" number: 2 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".greet.Book" json_name: "books" }
+	field { name: "etag" number: 3 type: TYPE_STRING json_name: "etag" }
+}
+service {
+	name: "LibraryService"
+	method { name: "UpdateBook" input_type: ".greet.UpdateBookRequest" output_type: ".greet.Book" }
+}
+`
+
+func TestHTTPRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules [][]byte
+		want  []HTTPRule
+		// err is a substring of the error, which is nil when empty.
+		err string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name: "nested path variable and body",
+			rules: [][]byte{httpRule(
+				httpRulePatch, "/v1/{book.name=books/*}",
+				httpRuleBody, "book",
+			)},
+			want: []HTTPRule{{
+				Method:   "PATCH",
+				Pattern:  "/v1/{book.name=books/*}",
+				Body:     "book",
+				Segments: []string{"v1", "books", "*"},
+				Vars:     []PathVar{{FieldPath: "book.name", Start: 1, End: 3}},
+			}},
+		},
+		{
+			name: "additional bindings and custom method",
+			rules: [][]byte{httpRule(
+				httpRulePut, "/v1/books/{book.name=**}",
+				httpRuleBody, "*",
+				httpRuleResponseBody, "",
+				httpRuleAdditionalBindings, httpRule(
+					httpRuleCustom, httpRule(
+						customHTTPPatternKind, "POST",
+						customHTTPPatternPath, "/v1/books:update",
+					),
+					httpRuleBody, "*",
+				),
+			)},
+			want: []HTTPRule{
+				{
+					Method:   "PUT",
+					Pattern:  "/v1/books/{book.name=**}",
+					Body:     "*",
+					Segments: []string{"v1", "books", "**"},
+					Vars:     []PathVar{{FieldPath: "book.name", Start: 2, End: -1}},
+				},
+				{
+					Method:   "POST",
+					Pattern:  "/v1/books:update",
+					Body:     "*",
+					Segments: []string{"v1", "books"},
+					Verb:     "update",
+				},
+			},
+		},
+		{
+			name: "additional bindings of additional bindings",
+			rules: [][]byte{httpRule(
+				httpRuleGet, "/v1/books",
+				httpRuleAdditionalBindings, httpRule(
+					httpRuleGet, "/v2/books",
+					httpRuleAdditionalBindings, httpRule(httpRuleGet, "/v3/books"),
+				),
+			)},
+			want: []HTTPRule{
+				{Method: "GET", Pattern: "/v1/books", Segments: []string{"v1", "books"}},
+				{Method: "GET", Pattern: "/v2/books", Segments: []string{"v2", "books"}},
+			},
+		},
+		{
+			name:  "no path",
+			rules: [][]byte{httpRule(httpRuleBody, "*")},
+			err:   "binding without HTTP method or path",
+		},
+		{
+			name:  "malformed path",
+			rules: [][]byte{httpRule(httpRuleGet, "/v1/{book.name")},
+			err:   `path "/v1/{book.name": unbalanced braces`,
+		},
+		{
+			name:  "unknown path variable",
+			rules: [][]byte{httpRule(httpRuleGet, "/v1/{book.title}")},
+			err:   "field path book.title: title is not a field of greet.Book",
+		},
+		{
+			name:  "path variable crossing a repeated field",
+			rules: [][]byte{httpRule(httpRuleGet, "/v1/{books.name}")},
+			err:   "field path books.name crosses non-message field books",
+		},
+		{
+			name:  "message path variable",
+			rules: [][]byte{httpRule(httpRuleGet, "/v1/{book}")},
+			err:   "path variable book must be a singular scalar field",
+		},
+		{
+			name:  "repeated path variable",
+			rules: [][]byte{httpRule(httpRuleGet, "/v1/{book.tags}")},
+			err:   "path variable book.tags must be a singular scalar field",
+		},
+		{
+			name:  "scalar body",
+			rules: [][]byte{httpRule(httpRulePost, "/v1/books", httpRuleBody, "etag")},
+			err:   "body etag must be a singular message field",
+		},
+		{
+			name:  "unknown response body",
+			rules: [][]byte{httpRule(httpRuleGet, "/v1/books", httpRuleResponseBody, "book")},
+			err:   "response_body book is not a field of greet.Book",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := newRequest(t, `
+				name: "greet.proto"
+				package: "greet"
+				options { go_package: "example.com/greet" }
+				`+bookMessages)
+			method := req.ProtoFile[0].Service[0].Method[0]
+			var unknown []byte
+			for _, rule := range test.rules {
+				unknown = protowire.AppendTag(unknown, httpRuleField, protowire.BytesType)
+				unknown = protowire.AppendBytes(unknown, rule)
+			}
+			// An unrelated unknown option is skipped.
+			unknown = protowire.AppendTag(unknown, 50000, protowire.VarintType)
+			unknown = protowire.AppendVarint(unknown, 1)
+			method.Options = &descriptorpb.MethodOptions{}
+			method.Options.ProtoReflect().SetUnknown(unknown)
+			plugin := newPlugin(t, req)
+
+			rules, err := httpRules(plugin.Files[0].Services[0].Methods[0])
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(rules, test.want) {
+				t.Errorf("rules %+v, want %+v", rules, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Values of the validate option besides "false".
+const (
+	// ValidateHandler validates requests before they reach the handler.
+	ValidateHandler = "true"
+	// ValidateAll validates requests in the client as well, before they are sent.
+	ValidateAll = "all"
+)
+
+// constraintExtensions are the numbers of the extensions declaring constraints
+// on message, oneof and field options: buf.validate.message, .oneof and .field
+// of protovalidate, and validate.disabled, .ignored, .required and .rules of
+// protoc-gen-validate. Neither is linked into the plugin, so they are usually
+// found among the unknown fields of the options.
+var constraintExtensions = map[protowire.Number]bool{
+	1159: true,
+	1071: true,
+	1072: true,
+}
+
+// validateHandler reports whether requests are validated on the handler side:
+// in the MethodFunc of ServiceInfo, the REST routes and the in-process client.
+func validateHandler() bool {
+	return *Server && (*Validate == ValidateHandler || *Validate == ValidateAll)
+}
+
+// validateClient reports whether {Service}Impl validates requests before
+// sending them.
+func validateClient() bool {
+	return *Client && *Validate == ValidateAll
+}
+
+// validates reports whether m gets its request validated. Only requests sent as
+// a single message are, those of unary and server streaming methods, and only
+// when their message or a message in their fields declares constraints: others
+// have nothing for protovalidate or a generated Validate method to check.
+func (m Method) validates() bool {
+	return !m.StreamsRequest && m.Input != nil && hasConstraints(m.Input, make(map[*protogen.Message]bool))
+}
+
+func hasValidation(s Service) bool {
+	for _, m := range s.Methods {
+		if m.validates() {
+			return true
+		}
+	}
+	return false
+}
+
+func hasConstraints(message *protogen.Message, seen map[*protogen.Message]bool) bool {
+	if seen[message] {
+		return false
+	}
+	seen[message] = true
+	if hasConstraintOption(message.Desc.Options()) {
+		return true
+	}
+	for _, oneof := range message.Oneofs {
+		if hasConstraintOption(oneof.Desc.Options()) {
+			return true
+		}
+	}
+	for _, field := range message.Fields {
+		if hasConstraintOption(field.Desc.Options()) {
+			return true
+		}
+		if field.Message != nil && hasConstraints(field.Message, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasConstraintOption reports whether opts set one of constraintExtensions,
+// known or not.
+func hasConstraintOption(opts proto.Message) bool {
+	if opts == nil || !opts.ProtoReflect().IsValid() {
+		return false
+	}
+	found := false
+	opts.ProtoReflect().Range(func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		found = field.IsExtension() && constraintExtensions[field.Number()]
+		return !found
+	})
+	b := opts.ProtoReflect().GetUnknown()
+	for !found && len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return false
+		}
+		b = b[n:]
+		found = constraintExtensions[num]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return false
+		}
+		b = b[n:]
+	}
+	return found
+}
+
+// genValidateCall writes the check of req at the start of a generated call,
+// with the validator found by the expression validator.
+func genValidateCall(g *protogen.GeneratedFile, s Service, validator, req string) {
+	g.P("if err := ", validateFunc(s), "(", validator, ", ", req, "); err != nil {")
+	g.P("return nil, err")
+	g.P("}")
+}
+
+// genHandlerValidateCall writes the check of req in a call of the handler hdlr,
+// validating with the validator hdlr was registered with.
+func genHandlerValidateCall(g *protogen.GeneratedFile, s Service, hdlr, req string) {
+	genValidateCall(g, s, handlerValidatorFunc(s)+"("+hdlr+")", req)
+}
+
+// genValidate writes the validation hooks of every service: the functions
+// setting a validator on a client or a handler, and the one applying it.
+func genValidate(g *protogen.GeneratedFile, t TripleGo) {
+	if !validateHandler() && !validateClient() {
+		return
+	}
+	protoMessage := protoPackage.Ident("Message")
+	tripleError := tripleProtocolPackage.Ident("Error")
+	for _, s := range t.Services {
+		if !hasValidation(s) {
+			continue
+		}
+		if validateClient() {
+			g.P("// With", s.GoName, "ClientValidator returns a copy of cli, as returned by New", s.GoName, ",")
+			g.P("// validating requests with validate before they are sent instead of with their")
+			g.P("// own Validate() error method. Other implementations of ", s.GoName, " are returned")
+			g.P("// unchanged.")
+			g.P("func With", s.GoName, "ClientValidator(cli ", s.GoName, ", validate func(", protoMessage, ") error) ", s.GoName, " {")
+			g.P("impl, ok := cli.(*", s.GoName, "Impl)")
+			g.P("if !ok {")
+			g.P("return cli")
+			g.P("}")
+			g.P("validated := *impl")
+			g.P("validated.validate = validate")
+			g.P("return &validated")
+			g.P("}")
+			g.P()
+		}
+		if validateHandler() {
+			validated := validatedHandler(s)
+			g.P("// With", s.GoName, "HandlerValidator returns hdlr with validate as its validator, for")
+			g.P("// Register", s.GoName, "Handler and the other functions serving a ", s.GoName, "Handler.")
+			g.P("// Requests are validated with it before they reach hdlr, instead of with their own")
+			g.P("// Validate() error method. A protovalidate validator plugs in as")
+			g.P("//")
+			g.P("//\tWith", s.GoName, "HandlerValidator(hdlr, func(msg proto.Message) error { return v.Validate(msg) })")
+			g.P("func With", s.GoName, "HandlerValidator(hdlr ", s.GoName, "Handler, validate func(", protoMessage, ") error) ", s.GoName, "Handler {")
+			g.P("if h, ok := hdlr.(*", validated, "); ok {")
+			g.P("hdlr = h.", s.GoName, "Handler")
+			g.P("}")
+			g.P("return &", validated, "{", s.GoName, "Handler: hdlr, validate: validate}")
+			g.P("}")
+			g.P()
+			g.P("type ", validated, " struct {")
+			g.P(s.GoName, "Handler")
+			g.P("validate func(", protoMessage, ") error")
+			g.P("}")
+			g.P()
+			g.P("// ", handlerValidatorFunc(s), " returns the validator hdlr was registered with, if any.")
+			g.P("func ", handlerValidatorFunc(s), "(hdlr ", s.GoName, "Handler) func(", protoMessage, ") error {")
+			g.P("if h, ok := hdlr.(*", validated, "); ok {")
+			g.P("return h.validate")
+			g.P("}")
+			g.P("return nil")
+			g.P("}")
+			g.P()
+		}
+		g.P("// ", validateFunc(s), " validates req with validate, or with its Validate() error method")
+		g.P("// when validate is nil. Only requests whose messages declare constraints get here.")
+		g.P("// A failed validation is returned as a CodeInvalidArgument error, unless the")
+		g.P("// validator returns a triple error itself. Violations of errors with a")
+		g.P("// ToProto() proto.Message method are attached to it as error detail.")
+		g.P("func ", validateFunc(s), "(validate func(", protoMessage, ") error, req ", protoMessage, ") error {")
+		g.P("if validate == nil {")
+		g.P("v, ok := req.(interface{ Validate() error })")
+		g.P("if !ok {")
+		g.P("return nil")
+		g.P("}")
+		g.P("validate = func(", protoMessage, ") error { return v.Validate() }")
+		g.P("}")
+		g.P("err := validate(req)")
+		g.P("if err == nil {")
+		g.P("return nil")
+		g.P("}")
+		g.P("var tripleErr *", tripleError)
+		g.P("if ", errorsPackage.Ident("As"), "(err, &tripleErr) {")
+		g.P("return err")
+		g.P("}")
+		g.P("tripleErr = ", tripleProtocolPackage.Ident("NewError"), "(", tripleProtocolPackage.Ident("CodeInvalidArgument"), ", err)")
+		g.P("var violations interface{ ToProto() ", protoMessage, " }")
+		g.P("if ", errorsPackage.Ident("As"), "(err, &violations) {")
+		g.P("if detail, detailErr := ", tripleProtocolPackage.Ident("NewErrorDetail"), "(violations.ToProto()); detailErr == nil {")
+		g.P("tripleErr.AddDetail(detail)")
+		g.P("}")
+		g.P("}")
+		g.P("return tripleErr")
+		g.P("}")
+		g.P()
+	}
+}
+
+func validateFunc(s Service) string {
+	return unexported(s.GoName + "Validate")
+}
+
+func validatedHandler(s Service) string {
+	return unexported(s.GoName + "ValidatedHandler")
+}
+
+func handlerValidatorFunc(s Service) string {
+	return unexported(s.GoName + "HandlerValidator")
+}
+
+// validateIdents lists the package-level identifiers genValidate declares for s.
+func validateIdents(s Service) []string {
+	if (!validateHandler() && !validateClient()) || !hasValidation(s) {
+		return nil
+	}
+	idents := []string{validateFunc(s)}
+	if validateClient() {
+		idents = append(idents, "With"+s.GoName+"ClientValidator")
+	}
+	if validateHandler() {
+		idents = append(idents, "With"+s.GoName+"HandlerValidator", validatedHandler(s), handlerValidatorFunc(s))
+	}
+	return idents
+}
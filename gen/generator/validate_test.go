@@ -0,0 +1,234 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// constrainedMessages declares messages of every shape constraints hide in. The
+// options carrying them are set by the tests.
+const constrainedMessages = `
+message_type {
+	name: "Plain"
+	field { name: "name" number: 1 type: TYPE_STRING json_name: "name" }
+}
+message_type {
+	name: "Recursive"
+	field { name: "name" number: 1 type: TYPE_STRING json_name: "name" }
+	field { name: "child" number: 2 type: TYPE_MESSAGE type_name: ".greet.Recursive" json_name: "child" }
+}
+message_type {
+	name: "Nested"
+	field { name: "plain" number: 1 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".greet.Plain" json_name: "plain" }
+	field { name: "byName" number: 2 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".greet.Nested.ByNameEntry" json_name: "byName" }
+	nested_type {
+		name: "ByNameEntry"
+		field { name: "key" number: 1 type: TYPE_STRING json_name: "key" }
+		field { name: "value" number: 2 type: TYPE_MESSAGE type_name: ".greet.Plain" json_name: "value" }
+		options { map_entry: true }
+	}
+}
+message_type {
+	name: "Choice"
+	field { name: "a" number: 1 type: TYPE_STRING oneof_index: 0 json_name: "a" }
+	field { name: "b" number: 2 type: TYPE_STRING oneof_index: 0 json_name: "b" }
+	oneof_decl { name: "pick" }
+}
+`
+
+// optionWith returns unknown option fields setting the extension num.
+func optionWith(num protowire.Number) []byte {
+	b := protowire.AppendTag(nil, num, protowire.BytesType)
+	return protowire.AppendBytes(b, nil)
+}
+
+func TestHasConstraints(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		// constrain sets constraints on the descriptors of the file.
+		constrain func(file *descriptorpb.FileDescriptorProto)
+		want      bool
+	}{
+		{
+			name:      "none",
+			message:   "Plain",
+			constrain: func(*descriptorpb.FileDescriptorProto) {},
+		},
+		{
+			name:    "other extension",
+			message: "Plain",
+			constrain: func(file *descriptorpb.FileDescriptorProto) {
+				file.MessageType[0].Field[0].Options = &descriptorpb.FieldOptions{}
+				file.MessageType[0].Field[0].Options.ProtoReflect().SetUnknown(optionWith(50000))
+			},
+		},
+		{
+			name:    "buf.validate field",
+			message: "Plain",
+			constrain: func(file *descriptorpb.FileDescriptorProto) {
+				file.MessageType[0].Field[0].Options = &descriptorpb.FieldOptions{Deprecated: proto.Bool(true)}
+				file.MessageType[0].Field[0].Options.ProtoReflect().SetUnknown(optionWith(1159))
+			},
+			want: true,
+		},
+		{
+			name:    "protoc-gen-validate field",
+			message: "Plain",
+			constrain: func(file *descriptorpb.FileDescriptorProto) {
+				file.MessageType[0].Field[0].Options = &descriptorpb.FieldOptions{}
+				file.MessageType[0].Field[0].Options.ProtoReflect().SetUnknown(optionWith(1071))
+			},
+			want: true,
+		},
+		{
+			name:    "buf.validate message",
+			message: "Plain",
+			constrain: func(file *descriptorpb.FileDescriptorProto) {
+				file.MessageType[0].Options = &descriptorpb.MessageOptions{}
+				file.MessageType[0].Options.ProtoReflect().SetUnknown(optionWith(1159))
+			},
+			want: true,
+		},
+		{
+			name:    "buf.validate oneof",
+			message: "Choice",
+			constrain: func(file *descriptorpb.FileDescriptorProto) {
+				file.MessageType[3].OneofDecl[0].Options = &descriptorpb.OneofOptions{}
+				file.MessageType[3].OneofDecl[0].Options.ProtoReflect().SetUnknown(optionWith(1159))
+			},
+			want: true,
+		},
+		{
+			name:    "repeated field of a constrained message",
+			message: "Nested",
+			constrain: func(file *descriptorpb.FileDescriptorProto) {
+				file.MessageType[0].Field[0].Options = &descriptorpb.FieldOptions{}
+				file.MessageType[0].Field[0].Options.ProtoReflect().SetUnknown(optionWith(1159))
+			},
+			want: true,
+		},
+		{
+			name:    "map value",
+			message: "Nested",
+			constrain: func(file *descriptorpb.FileDescriptorProto) {
+				value := file.MessageType[2].NestedType[0].Field[1]
+				value.Options = &descriptorpb.FieldOptions{}
+				value.Options.ProtoReflect().SetUnknown(optionWith(1159))
+			},
+			want: true,
+		},
+		{
+			name:    "recursive message",
+			message: "Recursive",
+			constrain: func(file *descriptorpb.FileDescriptorProto) {
+				file.MessageType[1].Field[1].Options = &descriptorpb.FieldOptions{Lazy: proto.Bool(true)}
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := newRequest(t, `
+				name: "greet.proto"
+				package: "greet"
+				options { go_package: "example.com/greet" }
+				`+constrainedMessages)
+			test.constrain(req.ProtoFile[0])
+			plugin := newPlugin(t, req)
+			var message *protogen.Message
+			for _, m := range plugin.Files[0].Messages {
+				if m.GoIdent.GoName == test.message {
+					message = m
+				}
+			}
+			if got := hasConstraints(message, make(map[*protogen.Message]bool)); got != test.want {
+				t.Errorf("hasConstraints(%s) = %v, want %v", test.message, got, test.want)
+			}
+		})
+	}
+}
+
+func TestGenValidate(t *testing.T) {
+	setParams(t, "validate=all", "inprocess=true")
+	req := newRequest(t, `
+		name: "greet.proto"
+		package: "greet"
+		options { go_package: "example.com/greet" }
+		`+greetMessages+`
+		message_type {
+			name: "CheckedRequest"
+			field { name: "name" number: 1 type: TYPE_STRING json_name: "name" }
+		}
+		service {
+			name: "GreetService"
+			method { name: "Greet" input_type: ".greet.GreetRequest" output_type: ".greet.GreetResponse" }
+			method { name: "Check" input_type: ".greet.CheckedRequest" output_type: ".greet.GreetResponse" }
+		}`)
+	field := req.ProtoFile[0].MessageType[2].Field[0]
+	field.Options = &descriptorpb.FieldOptions{}
+	field.Options.ProtoReflect().SetUnknown(optionWith(1159))
+	stubs := generate(t, newPlugin(t, req), "greet.proto")
+
+	for _, want := range []string{
+		// The validator is set per client and per handler.
+		"func WithGreetServiceClientValidator(cli GreetService, validate func(proto.Message) error) GreetService {",
+		"func WithGreetServiceHandlerValidator(hdlr GreetServiceHandler, validate func(proto.Message) error) GreetServiceHandler {",
+		"if err := greetServiceValidate(c.validate, req); err != nil {",
+		"if err := greetServiceValidate(greetServiceHandlerValidator(handler.(GreetServiceHandler)), req); err != nil {",
+		// Violations are found with errors.As, which walks joined errors.
+		"var violations interface{ ToProto() proto.Message }",
+		"if errors.As(err, &violations) {",
+	} {
+		if !strings.Contains(stubs, want) {
+			t.Errorf("generated stubs lack %q", want)
+		}
+	}
+	if strings.Contains(stubs, `"reflect"`) {
+		t.Error("generated stubs import reflect")
+	}
+	if strings.Contains(stubs, "var GreetServiceValidator") {
+		t.Error("generated stubs declare a package-level validator")
+	}
+	// Only the request with constraints is validated: in the client, in the
+	// MethodFunc of the handler and in the in-process client.
+	if n := strings.Count(stubs, "greetServiceValidate("); n != 4 {
+		t.Errorf("greetServiceValidate declared and called %d times, want 4", n)
+	}
+}
+
+func TestGenValidateWithoutConstraints(t *testing.T) {
+	setParams(t, "validate=all")
+	plugin := newPlugin(t, newRequest(t, `
+		name: "greet.proto"
+		package: "greet"
+		options { go_package: "example.com/greet" }
+		`+greetMessages+greetStreams))
+	stubs := generate(t, plugin, "greet.proto")
+	if strings.Contains(stubs, "Validat") {
+		t.Error("requests without constraints are validated")
+	}
+}
@@ -61,12 +61,33 @@ func main() {
 	var flags flag.FlagSet
 	useOld := flags.Bool("useOldVersion", false, "print the version and exit")
 	old_triple.RequireUnimplemented = flags.Bool("require_unimplemented_servers", true, "set to false to match legacy behavior")
+	generator.RequireUnimplemented = flags.Bool("require_unimplemented_handlers", false, "require handler implementations to embed Unimplemented{Service}Handler")
+	generator.WarnDeprecated = flags.Bool("warn_deprecated", false, "log a warning the first time a deprecated RPC is called")
+	generator.PackageSuffix = flags.String("package_suffix", "", "generate stubs into a sub-package named after the message package plus this suffix")
+	generator.Client = flags.Bool("client", true, "set to false to leave out the client interface and its implementation")
+	generator.Server = flags.Bool("server", true, "set to false to leave out the handler interface and its registration")
+	generator.PerService = flags.Bool("per_service", false, "write the stubs of every service to a file of its own")
+	generator.FileSuffix = flags.String("file_suffix", ".triple.go", "suffix of the generated file names")
+	generator.Mocks = flags.Bool("mocks", false, "generate mocks of the client and handler interfaces into a _mock.go file")
+	generator.OpenAPI = flags.String("openapi", "false", "set to true for an OpenAPI document per proto file, or to merged for a single one")
+	generator.Validate = flags.String("validate", "false", "set to true to validate requests before they reach the handler, or to all to validate them in the client too")
+	generator.InterfaceName = flags.String("interface_name", "proto", "set to java to register services under the name of their Java interface")
+	generator.Iterators = flags.String("iterators", "false", "set to true to add All iterators to the receiving stream interfaces, or to func to declare them without the iter package")
+	generator.InProcess = flags.Bool("inprocess", false, "generate New{Service}InProcess, a client calling a handler in the same process, for tests")
+	generator.IdempotentRetries = flags.Int("idempotent_retries", 0, "retry unary calls to IDEMPOTENT and NO_SIDE_EFFECTS methods this many times unless the dubbo options set the retries")
+	flags.Var(&generator.Include, "include", "only generate services and methods matching this glob, may be repeated")
+	flags.Var(&generator.Exclude, "exclude", "do not generate services and methods matching this glob, may be repeated")
 
-	protogen.Options{
+	run(protogen.Options{
 		ParamFunc: flags.Set,
-	}.Run(
+	},
 		func(plugin *protogen.Plugin) error {
-			plugin.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+			plugin.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL |
+				pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS)
+			// Both generators only look at services and methods, whose shape does not
+			// depend on edition features, so every edition protoc-gen-go knows is fine.
+			plugin.SupportedEditionsMinimum = descriptorpb.Edition_EDITION_PROTO2
+			plugin.SupportedEditionsMaximum = descriptorpb.Edition_EDITION_2023
 			if *useOld {
 				return genOldTriple(plugin)
 			}
@@ -78,11 +99,14 @@ func main() {
 func genTriple(plugin *protogen.Plugin) error {
 	var errors []error
 
-	allFiles := make([]*descriptorpb.FileDescriptorProto, 0, len(plugin.Files))
-	for _, file := range plugin.Files {
-		allFiles = append(allFiles, file.Proto)
+	if err := generator.CheckOptions(); err != nil {
+		return err
+	}
+	if err := generator.CheckCollisions(plugin); err != nil {
+		return err
 	}
 
+	var openAPI []generator.TripleGo
 	for _, file := range plugin.Files {
 		// Skip files that are not marked for generation
 		if !file.Generate {
@@ -94,28 +118,52 @@ func genTriple(plugin *protogen.Plugin) error {
 			continue
 		}
 
-		tripleGo, err := generator.ProcessProtoFile(file.Proto, allFiles)
+		tripleGo, err := generator.ProcessProtoFile(plugin, file)
 		if err != nil {
 			errors = append(errors, fmt.Errorf("processing %s: %w", file.Desc.Path(), err))
 			continue
 		}
-		// Ensure the generated file uses the exact Go package name computed by protoc-gen-go.
-		tripleGo.Package = string(file.GoPackageName)
-		filename := file.GeneratedFilenamePrefix + ".triple.go"
-		// Use the same import path as the pb.go file to ensure they're in the same package
-		// Extract the package name from the go_package option
-		goPackage := file.Proto.Options.GetGoPackage()
-		var importPath protogen.GoImportPath
-		if goPackage != "" {
-			parts := strings.Split(goPackage, ";")
-			importPath = protogen.GoImportPath(parts[0])
-		} else {
-			importPath = file.GoImportPath
+		// Skip files whose services are all filtered out
+		if len(tripleGo.Services) == 0 {
+			continue
 		}
-		g := plugin.NewGeneratedFile(filename, importPath)
-		err = generator.GenTripleFile(g, tripleGo)
-		if err != nil {
-			errors = append(errors, fmt.Errorf("generating %s: %w", filename, err))
+		// Ensure the generated file uses the exact Go package name and import path
+		// computed by protoc-gen-go, which already honors M<file>=<importpath> and
+		// module= parameters, or the sub-package derived from them.
+		packageName, importPath := generator.GoPackage(file)
+		tripleGo.Package = string(packageName)
+		switch *generator.OpenAPI {
+		case generator.OpenAPIPerFile:
+			filename := generator.OpenAPIFilename(file)
+			title := tripleGo.ProtoPackage
+			if title == "" {
+				title = tripleGo.Source
+			}
+			if err := generator.GenOpenAPIFile(plugin.NewGeneratedFile(filename, importPath), title, []generator.TripleGo{tripleGo}); err != nil {
+				errors = append(errors, fmt.Errorf("generating %s: %w", filename, err))
+			}
+		case generator.OpenAPIMerged:
+			openAPI = append(openAPI, tripleGo)
+		}
+		for _, out := range generator.SplitFiles(file, tripleGo) {
+			g := plugin.NewGeneratedFile(out.Filename, importPath)
+			if err := generator.GenTripleFile(g, out.TripleGo); err != nil {
+				errors = append(errors, fmt.Errorf("generating %s: %w", out.Filename, err))
+			}
+			if !*generator.Mocks {
+				continue
+			}
+			mockFilename := generator.MockFilename(out.Filename)
+			mock := plugin.NewGeneratedFile(mockFilename, importPath)
+			if err := generator.GenMockFile(mock, out.TripleGo); err != nil {
+				errors = append(errors, fmt.Errorf("generating %s: %w", mockFilename, err))
+			}
+		}
+	}
+	if len(openAPI) > 0 {
+		g := plugin.NewGeneratedFile(generator.OpenAPIMergedFilename, "")
+		if err := generator.GenOpenAPIFile(g, "Triple services", openAPI); err != nil {
+			errors = append(errors, fmt.Errorf("generating %s: %w", generator.OpenAPIMergedFilename, err))
 		}
 	}
 	if len(errors) > 0 {
@@ -0,0 +1,114 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// run behaves like protogen.Options.Run, but rewrites the legacy import_path
+// parameter before protogen resolves import paths.
+func run(opts protogen.Options, f func(*protogen.Plugin) error) {
+	if err := runWithOptions(opts, f); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", filepath.Base(os.Args[0]), err)
+		os.Exit(1)
+	}
+}
+
+func runWithOptions(opts protogen.Options, f func(*protogen.Plugin) error) error {
+	in, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(in, req); err != nil {
+		return err
+	}
+	applyImportPath(req)
+	gen, err := opts.New(req)
+	if err != nil {
+		return err
+	}
+	if err := f(gen); err != nil {
+		// Errors from the plugin function are reported by setting the
+		// error field in the CodeGeneratorResponse.
+		//
+		// In contrast, errors that indicate a problem in protoc
+		// itself (unparsable input, I/O errors, etc.) are reported
+		// to stderr.
+		gen.Error(err)
+	}
+	resp := gen.Response()
+	out, err := proto.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stdout.Write(out); err != nil {
+		return err
+	}
+	return nil
+}
+
+// applyImportPath implements the import_path parameter of the legacy
+// protoc-gen-go: files being generated that have neither a go_package option
+// nor an M<file>=<importpath> mapping are placed in that import path. The
+// parameter is turned into M mappings, so protogen resolves it like any other.
+func applyImportPath(req *pluginpb.CodeGeneratorRequest) {
+	var (
+		importPath string
+		params     []string
+		mapped     = make(map[string]bool)
+	)
+	for _, param := range strings.Split(req.GetParameter(), ",") {
+		key, value, _ := strings.Cut(param, "=")
+		switch {
+		case key == "import_path":
+			importPath = value
+			continue
+		case strings.HasPrefix(key, "M"):
+			mapped[key[1:]] = true
+		}
+		params = append(params, param)
+	}
+	if importPath == "" {
+		return
+	}
+
+	withGoPackage := make(map[string]bool)
+	for _, file := range req.GetProtoFile() {
+		if file.GetOptions().GetGoPackage() != "" {
+			withGoPackage[file.GetName()] = true
+		}
+	}
+	for _, name := range req.GetFileToGenerate() {
+		if !withGoPackage[name] && !mapped[name] {
+			params = append(params, "M"+name+"="+importPath)
+		}
+	}
+	req.Parameter = proto.String(strings.Join(params, ","))
+}
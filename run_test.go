@@ -0,0 +1,147 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"testing"
+)
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestApplyImportPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		parameter string
+		goPackage string
+		// wantImportPath is the import path protogen resolves for
+		// greet/greet.proto, and wantFile the name of its stubs.
+		wantImportPath string
+		wantFile       string
+	}{
+		{
+			name:           "go_package",
+			goPackage:      "example.com/gopackage;greet",
+			wantImportPath: "example.com/gopackage",
+			wantFile:       "example.com/gopackage/greet.triple.go",
+		},
+		{
+			name:           "import_path without go_package",
+			parameter:      "import_path=example.com/importpath",
+			wantImportPath: "example.com/importpath",
+			wantFile:       "example.com/importpath/greet.triple.go",
+		},
+		{
+			name:           "go_package over import_path",
+			parameter:      "import_path=example.com/importpath",
+			goPackage:      "example.com/gopackage;greet",
+			wantImportPath: "example.com/gopackage",
+			wantFile:       "example.com/gopackage/greet.triple.go",
+		},
+		{
+			name:           "M over go_package",
+			parameter:      "Mgreet/greet.proto=example.com/mapped",
+			goPackage:      "example.com/gopackage;greet",
+			wantImportPath: "example.com/mapped",
+			wantFile:       "example.com/mapped/greet.triple.go",
+		},
+		{
+			name:           "M over go_package and import_path",
+			parameter:      "import_path=example.com/importpath,Mgreet/greet.proto=example.com/mapped",
+			goPackage:      "example.com/gopackage;greet",
+			wantImportPath: "example.com/mapped",
+			wantFile:       "example.com/mapped/greet.triple.go",
+		},
+		{
+			name:           "M over import_path",
+			parameter:      "Mgreet/greet.proto=example.com/mapped,import_path=example.com/importpath",
+			wantImportPath: "example.com/mapped",
+			wantFile:       "example.com/mapped/greet.triple.go",
+		},
+		{
+			name:           "import_path with module",
+			parameter:      "import_path=example.com/importpath,module=example.com",
+			wantImportPath: "example.com/importpath",
+			wantFile:       "importpath/greet.triple.go",
+		},
+		{
+			name:           "import_path with source relative paths",
+			parameter:      "import_path=example.com/importpath,paths=source_relative",
+			wantImportPath: "example.com/importpath",
+			wantFile:       "greet/greet.triple.go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := &descriptorpb.FileDescriptorProto{}
+			if err := prototext.Unmarshal([]byte(`name: "greet/greet.proto" package: "greet"`), file); err != nil {
+				t.Fatal(err)
+			}
+			if tt.goPackage != "" {
+				file.Options = &descriptorpb.FileOptions{GoPackage: &tt.goPackage}
+			}
+			req := &pluginpb.CodeGeneratorRequest{
+				Parameter:      &tt.parameter,
+				FileToGenerate: []string{file.GetName()},
+				ProtoFile:      []*descriptorpb.FileDescriptorProto{file},
+			}
+			applyImportPath(req)
+			plugin, err := protogen.Options{}.New(req)
+			if err != nil {
+				t.Fatalf("protogen: %v", err)
+			}
+			got := plugin.FilesByPath[file.GetName()]
+			if string(got.GoImportPath) != tt.wantImportPath {
+				t.Errorf("import path = %s, want %s", got.GoImportPath, tt.wantImportPath)
+			}
+			g := plugin.NewGeneratedFile(got.GeneratedFilenamePrefix+".triple.go", got.GoImportPath)
+			g.P("package greet")
+			resp := plugin.Response()
+			if resp.Error != nil {
+				t.Fatalf("protogen: %s", resp.GetError())
+			}
+			if name := resp.File[0].GetName(); name != tt.wantFile {
+				t.Errorf("generated file = %s, want %s", name, tt.wantFile)
+			}
+		})
+	}
+}
+
+// TestApplyImportPathDependencies checks that import_path only applies to the
+// files being generated, as in the legacy protoc-gen-go.
+func TestApplyImportPathDependencies(t *testing.T) {
+	parameter := "import_path=example.com/importpath,Mcommon.proto=example.com/common"
+	req := &pluginpb.CodeGeneratorRequest{
+		Parameter:      &parameter,
+		FileToGenerate: []string{"greet.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("common.proto"), Package: proto.String("common")},
+			{Name: proto.String("greet.proto"), Package: proto.String("greet"), Dependency: []string{"common.proto"}},
+		},
+	}
+	applyImportPath(req)
+	want := "Mcommon.proto=example.com/common,Mgreet.proto=example.com/importpath"
+	if got := req.GetParameter(); got != want {
+		t.Fatalf("parameter = %s, want %s", got, want)
+	}
+}
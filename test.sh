@@ -20,12 +20,13 @@ set -euo pipefail
 # Save the root directory where the script is located
 SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
 
-# Ensure the protoc-gen-go-triple binary exists locally
+# The dubbo-go version the generated stubs are compiled and tested against
+DUBBO_GO_VERSION="${DUBBO_GO_VERSION:-v3.3.0}"
+
+# Build the protoc-gen-go-triple binary from the current sources
 PLUGIN="$SCRIPT_DIR/protoc-gen-go-triple"
-if [ ! -x "$PLUGIN" ]; then
-    echo "Building protoc-gen-go-triple..."
-    (cd "$SCRIPT_DIR" && go build -o "$PLUGIN")
-fi
+echo "Building protoc-gen-go-triple..."
+(cd "$SCRIPT_DIR" && go build -o "$PLUGIN")
 
 for dir in ./test/correctly/*/; do
     if [ ! -d "$dir" ]; then
@@ -56,10 +57,16 @@ for dir in ./test/correctly/*/; do
         fi
     else
         if [ -f "./proto/greet.proto" ]; then
-            protoc -I=proto \
-              --go_out=. --go_opt=paths=source_relative \
+            # Extra plugin options of the fixture, like mocks=true
+            triple_opt="paths=source_relative"
+            if [ -f "./triple_opt" ]; then
+                triple_opt="$triple_opt,$(cat ./triple_opt)"
+            fi
+            # The repository root provides dubbo/options.proto
+            protoc -I=proto -I="$SCRIPT_DIR" \
+              --go_out=proto --go_opt=paths=source_relative \
               --plugin=protoc-gen-go-triple="$PLUGIN" \
-              --go-triple_out=. --go-triple_opt=paths=source_relative \
+              --go-triple_out=proto --go-triple_opt="$triple_opt" \
               ./proto/greet.proto
         else
             echo "Warning: greet.proto not found in $dir_name"
@@ -68,13 +75,14 @@ for dir in ./test/correctly/*/; do
         fi
     fi
     
-    # Run 'go mod tidy' only where a go.mod exists
+    # Run 'go mod tidy' only where a go.mod exists, with dubbo-go pinned
     if [ "$dir_name" = "import_nested" ]; then
         if [ -f "proto/go.mod" ]; then
-            (cd proto && go mod tidy)
+            (cd proto && go mod edit -require="dubbo.apache.org/dubbo-go/v3@$DUBBO_GO_VERSION" && go mod tidy)
         fi
     else
         if [ -f "go.mod" ]; then
+            go mod edit -require="dubbo.apache.org/dubbo-go/v3@$DUBBO_GO_VERSION"
             go mod tidy
         fi
     fi
@@ -89,7 +97,8 @@ for dir in ./test/correctly/*/; do
         fi
     else
         if [ -d "./proto" ]; then
-            (cd proto && go vet ./...)
+            # Also run the tests of fixtures exercising the generated code
+            (cd proto && go vet ./... && go test ./...)
         else
             echo "Warning: proto directory not found in $dir_name"
             cd "$SCRIPT_DIR" || exit 1
@@ -0,0 +1,3 @@
+module editions
+
+go 1.22
@@ -0,0 +1,5 @@
+module http_rules
+
+go 1.22
+
+replace github.com/dubbogo/protoc-gen-go-triple/v3 => ../../..
@@ -0,0 +1,290 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package greet
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+import (
+	"dubbo.apache.org/dubbo-go/v3/client"
+	"dubbo.apache.org/dubbo-go/v3/protocol/triple/triple_protocol"
+)
+
+func TestInProcessUnary(t *testing.T) {
+	h := &MockLibraryServiceHandler{
+		GetBookFunc: func(ctx context.Context, req *GetBookRequest) (*Book, error) {
+			if req.Name == "missing" {
+				return nil, NewGetBookBookNotFoundError("no such book", &BookNotFound{Name: req.Name})
+			}
+			req.Name = "changed by the handler"
+			return &Book{Name: "shelves/1/books/2", Title: "Dune"}, nil
+		},
+	}
+	cli := NewLibraryServiceInProcess(h)
+
+	req := &GetBookRequest{Name: "shelves/1/books/2"}
+	book, err := cli.GetBook(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.Title != "Dune" || req.Name != "shelves/1/books/2" {
+		t.Errorf("book %v, request %v", book, req)
+	}
+	h.ExpectGetBookCalls(t, 1)
+
+	_, err = cli.GetBook(context.Background(), &GetBookRequest{Name: "missing"})
+	if triple_protocol.CodeOf(err) != triple_protocol.CodeNotFound {
+		t.Errorf("error %v, want not_found", err)
+	}
+	if detail, ok := AsBookNotFound(err); !ok || detail.Name != "missing" {
+		t.Errorf("error %v carries detail %v", err, detail)
+	}
+
+	_, err = cli.UpdateBook(context.Background(), &UpdateBookRequest{})
+	if triple_protocol.CodeOf(err) != triple_protocol.CodeUnimplemented {
+		t.Errorf("unstubbed method: error %v, want unimplemented", err)
+	}
+}
+
+func TestInProcessStreams(t *testing.T) {
+	h := &MockLibraryServiceHandler{
+		WatchBooksFunc: func(ctx context.Context, req *ListBooksRequest, stream LibraryService_WatchBooksServer) error {
+			for _, tag := range req.Tags {
+				if err := stream.Send(&Book{Tags: []string{tag}}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		ImportBooksFunc: func(ctx context.Context, stream LibraryService_ImportBooksServer) (*ListBooksResponse, error) {
+			res := &ListBooksResponse{}
+			for stream.Recv() {
+				res.Books = append(res.Books, stream.Msg())
+			}
+			return res, stream.Err()
+		},
+		SyncBooksFunc: func(ctx context.Context, stream LibraryService_SyncBooksServer) error {
+			for {
+				book, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				book.Title += " (synced)"
+				if err := stream.Send(book); err != nil {
+					return err
+				}
+			}
+		},
+	}
+	cli := NewLibraryServiceInProcess(h)
+	ctx := context.Background()
+
+	watch, err := cli.WatchBooks(ctx, &ListBooksRequest{Tags: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tags []string
+	for watch.Recv() {
+		tags = append(tags, watch.Msg().Tags...)
+	}
+	if err := watch.Err(); err != nil || len(tags) != 2 {
+		t.Errorf("WatchBooks received %v, %v", tags, err)
+	}
+	_ = watch.Close()
+
+	imports, err := cli.ImportBooks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, title := range []string{"Dune", "Emma"} {
+		if err := imports.Send(&Book{Title: title}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	res, err := imports.CloseAndRecv()
+	if err != nil || len(res.Books) != 2 {
+		t.Errorf("ImportBooks returned %v, %v", res, err)
+	}
+
+	bidi, err := cli.SyncBooks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bidi.Send(&Book{Title: "Dune"}); err != nil {
+		t.Fatal(err)
+	}
+	book, err := bidi.Recv()
+	if err != nil || book.Title != "Dune (synced)" {
+		t.Errorf("SyncBooks received %v, %v", book, err)
+	}
+	if err := bidi.CloseRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bidi.Recv(); !errors.Is(err, io.EOF) {
+		t.Errorf("SyncBooks ended with %v, want io.EOF", err)
+	}
+	_ = bidi.CloseResponse()
+
+	// Sending twice before receiving does not deadlock against the echoing
+	// handler, and nothing sent is lost when either side closes.
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	bidi, err = cli.SyncBooks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, title := range []string{"Dune", "Emma"} {
+		if err := bidi.Send(&Book{Title: title}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := bidi.CloseRequest(); err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for {
+		book, err := bidi.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		titles = append(titles, book.Title)
+	}
+	if len(titles) != 2 || titles[0] != "Dune (synced)" || titles[1] != "Emma (synced)" {
+		t.Errorf("SyncBooks received %v", titles)
+	}
+	_ = bidi.CloseResponse()
+}
+
+func TestInProcessHandlerErrors(t *testing.T) {
+	h := &MockLibraryServiceHandler{
+		GetBookFunc: func(ctx context.Context, req *GetBookRequest) (*Book, error) {
+			return nil, errors.New("disk on fire")
+		},
+		WatchBooksFunc: func(ctx context.Context, req *ListBooksRequest, stream LibraryService_WatchBooksServer) error {
+			return errors.New("disk on fire")
+		},
+	}
+	cli := NewLibraryServiceInProcess(h)
+
+	// Errors other than triple errors reach the caller as CodeUnknown, as they
+	// would over the network.
+	_, err := cli.GetBook(context.Background(), &GetBookRequest{Name: "shelves/1/books/2"})
+	if triple_protocol.CodeOf(err) != triple_protocol.CodeUnknown {
+		t.Errorf("GetBook failed with %v, want unknown", err)
+	}
+	watch, err := cli.WatchBooks(context.Background(), &ListBooksRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for watch.Recv() {
+	}
+	if triple_protocol.CodeOf(watch.Err()) != triple_protocol.CodeUnknown {
+		t.Errorf("WatchBooks failed with %v, want unknown", watch.Err())
+	}
+	_ = watch.Close()
+}
+
+func TestMockClient(t *testing.T) {
+	var svc LibraryService = &MockLibraryService{
+		ListBooksFunc: func(ctx context.Context, req *ListBooksRequest, opts ...client.CallOption) (*ListBooksResponse, error) {
+			return &ListBooksResponse{Books: []*Book{{Name: req.Parent + "/books/1"}}}, nil
+		},
+	}
+	res, err := svc.ListBooks(context.Background(), &ListBooksRequest{Parent: "shelves/1"})
+	if err != nil || len(res.Books) != 1 {
+		t.Fatalf("ListBooks returned %v, %v", res, err)
+	}
+	mock := svc.(*MockLibraryService)
+	mock.ExpectListBooksCalls(t, 1)
+	if calls := mock.ListBooksCalls(); calls[0].Req.Parent != "shelves/1" {
+		t.Errorf("recorded call %v", calls[0])
+	}
+}
+
+func TestInProcessClose(t *testing.T) {
+	stopped := make(chan struct{})
+	h := &MockLibraryServiceHandler{
+		WatchBooksFunc: func(ctx context.Context, req *ListBooksRequest, stream LibraryService_WatchBooksServer) error {
+			defer close(stopped)
+			if stream.Conn().ExportableHeader() == nil {
+				return errors.New("no exportable header")
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	cli := NewLibraryServiceInProcess(h)
+
+	watch, err := cli.WatchBooks(context.Background(), &ListBooksRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Close stops the handlers of the streams still open.
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not stop the handler")
+	}
+	for watch.Recv() {
+	}
+	if triple_protocol.CodeOf(watch.Err()) != triple_protocol.CodeCanceled {
+		t.Errorf("WatchBooks failed with %v, want canceled", watch.Err())
+	}
+
+	// Calls after Close fail.
+	if _, err := cli.GetBook(context.Background(), &GetBookRequest{}); triple_protocol.CodeOf(err) != triple_protocol.CodeCanceled {
+		t.Errorf("GetBook after Close failed with %v, want canceled", err)
+	}
+	if _, err := cli.ImportBooks(context.Background()); triple_protocol.CodeOf(err) != triple_protocol.CodeCanceled {
+		t.Errorf("ImportBooks after Close failed with %v, want canceled", err)
+	}
+}
+
+func TestFakeServerStream(t *testing.T) {
+	h := &MockLibraryServiceHandler{
+		WatchBooksFunc: func(ctx context.Context, req *ListBooksRequest, stream LibraryService_WatchBooksServer) error {
+			stream.Conn().ResponseHeader().Set("Shelf", stream.Conn().RequestHeader().Get("Shelf"))
+			return stream.Send(&Book{Name: "shelves/1/books/2"})
+		},
+	}
+	stream := &FakeLibraryServiceWatchBooksServer{ReqHeader: http.Header{"Shelf": {"1"}}}
+	if err := h.WatchBooks(context.Background(), &ListBooksRequest{}, stream); err != nil {
+		t.Fatal(err)
+	}
+	if len(stream.Sent) != 1 || stream.ResponseHeader().Get("Shelf") != "1" {
+		t.Errorf("sent %v with header %v", stream.Sent, stream.ResponseHeader())
+	}
+	if spec := stream.Conn().Spec(); spec.Procedure != LibraryServiceWatchBooksProcedure || spec.StreamType != triple_protocol.StreamTypeServer {
+		t.Errorf("Conn has spec %+v", spec)
+	}
+}
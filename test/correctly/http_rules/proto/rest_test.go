@@ -0,0 +1,261 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package greet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// serveREST sends a request to the REST handler of h and returns the response.
+func serveREST(t *testing.T, h LibraryServiceHandler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	NewLibraryServiceRESTHandler(h).ServeHTTP(w, r)
+	return w
+}
+
+func TestRESTRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		// want is the request the handler gets, in JSON.
+		want string
+	}{
+		{
+			name:   "path variable",
+			method: http.MethodGet,
+			target: "/v1/shelves/1/books/2",
+			want:   `{"name": "shelves/1/books/2"}`,
+		},
+		{
+			name:   "escaped path variable",
+			method: http.MethodGet,
+			target: "/v1/shelves/a%20b/books/2",
+			want:   `{"name": "shelves/a b/books/2"}`,
+		},
+		{
+			name:   "catch-all path variable",
+			method: http.MethodGet,
+			target: "/v1/files/docs/a/b.txt",
+			want:   `{"path": "docs/a/b.txt"}`,
+		},
+		{
+			// Values of several segments keep escaped slashes, which would
+			// otherwise read as separators.
+			name:   "escaped slash in a catch-all path variable",
+			method: http.MethodGet,
+			target: "/v1/files/docs%2Fa/b%20c.txt",
+			want:   `{"path": "docs%2Fa/b c.txt"}`,
+		},
+		{
+			name:   "escaped slash in a path variable of several segments",
+			method: http.MethodGet,
+			target: "/v1/shelves/a%2fb/books/2",
+			want:   `{"name": "shelves/a%2Fb/books/2"}`,
+		},
+		{
+			name:   "verb",
+			method: http.MethodGet,
+			target: "/v1/shelves/1:featured",
+			want:   `{"name": "shelves/1"}`,
+		},
+		{
+			name:   "query parameters",
+			method: http.MethodGet,
+			target: "/v1/shelves/1/books?pageSize=10&tags=a&tags=b&order=ORDER_NEWEST&descending=true&cursor=YWJj&min_rating=4.5&filter.author=me&filter.year=2020",
+			want: `{"parent": "shelves/1", "pageSize": 10, "tags": ["a", "b"], "order": "ORDER_NEWEST", "descending": true,
+				"cursor": "YWJj", "minRating": 4.5, "filter": {"author": "me", "year": "2020"}}`,
+		},
+		{
+			name:   "enum number",
+			method: http.MethodGet,
+			target: "/v1/shelves/1/books?order=1",
+			want:   `{"parent": "shelves/1", "order": "ORDER_TITLE"}`,
+		},
+		{
+			name:   "field body",
+			method: http.MethodPost,
+			target: "/v1/shelves/1/books",
+			body:   `{"title": "Dune", "tags": ["sf"]}`,
+			want:   `{"parent": "shelves/1", "book": {"title": "Dune", "tags": ["sf"]}}`,
+		},
+		{
+			name:   "nested path variable into the body",
+			method: http.MethodPatch,
+			target: "/v1/shelves/1/books/2?allowMissing=true",
+			body:   `{"title": "Dune"}`,
+			want:   `{"book": {"name": "shelves/1/books/2", "title": "Dune"}, "allowMissing": true}`,
+		},
+		{
+			name:   "additional binding with the whole body",
+			method: http.MethodPut,
+			target: "/v1/books/shelves/1/books/2",
+			body:   `{"book": {"title": "Dune"}, "allowMissing": true}`,
+			want:   `{"book": {"name": "shelves/1/books/2", "title": "Dune"}, "allowMissing": true}`,
+		},
+		{
+			name:   "custom method with verb",
+			method: http.MethodPost,
+			target: "/v1/shelves/1/books/2:publish",
+			body:   `{"edition": "second"}`,
+			want:   `{"name": "shelves/1/books/2", "edition": "second"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got proto.Message
+			record := func(req proto.Message) {
+				got = req
+			}
+			h := &MockLibraryServiceHandler{
+				GetBookFunc: func(ctx context.Context, req *GetBookRequest) (*Book, error) {
+					record(req)
+					return &Book{Name: req.Name}, nil
+				},
+				GetFeaturedFunc: func(ctx context.Context, req *GetShelfRequest) (*Shelf, error) {
+					record(req)
+					return &Shelf{Name: req.Name}, nil
+				},
+				GetFileFunc: func(ctx context.Context, req *GetFileRequest) (*File, error) {
+					record(req)
+					return &File{Path: req.Path}, nil
+				},
+				ListBooksFunc: func(ctx context.Context, req *ListBooksRequest) (*ListBooksResponse, error) {
+					record(req)
+					return &ListBooksResponse{}, nil
+				},
+				CreateBookFunc: func(ctx context.Context, req *CreateBookRequest) (*Book, error) {
+					record(req)
+					return req.Book, nil
+				},
+				UpdateBookFunc: func(ctx context.Context, req *UpdateBookRequest) (*Book, error) {
+					record(req)
+					return req.Book, nil
+				},
+				PublishBookFunc: func(ctx context.Context, req *PublishBookRequest) (*Book, error) {
+					record(req)
+					return &Book{Name: req.Name}, nil
+				},
+			}
+			w := serveREST(t, h, test.method, test.target, test.body)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			if got == nil {
+				t.Fatal("handler not called")
+			}
+			want := got.ProtoReflect().New().Interface()
+			if err := protojson.Unmarshal([]byte(test.want), want); err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(got, want) {
+				t.Errorf("request %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRESTResponses(t *testing.T) {
+	h := &MockLibraryServiceHandler{
+		GetBookFunc: func(ctx context.Context, req *GetBookRequest) (*Book, error) {
+			return nil, NewGetBookBookNotFoundError("no such book", &BookNotFound{Name: req.Name})
+		},
+		GetFeaturedFunc: func(ctx context.Context, req *GetShelfRequest) (*Shelf, error) {
+			return &Shelf{Name: req.Name, Featured: &Book{Title: "Dune"}}, nil
+		},
+	}
+
+	// The response body selects a field of the response.
+	w := serveREST(t, h, http.MethodGet, "/v1/shelves/1:featured", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	featured := &Book{}
+	if err := protojson.Unmarshal(w.Body.Bytes(), featured); err != nil || featured.Title != "Dune" {
+		t.Errorf("response %s is not the featured book: %v", w.Body, err)
+	}
+
+	// Errors are written as JSON with the HTTP status of their code.
+	for _, test := range []struct {
+		method, target string
+		status         int
+		code           string
+	}{
+		{http.MethodGet, "/v1/shelves/1/books/2", http.StatusNotFound, "not_found"},
+		{http.MethodGet, "/v1/shelves/1/books?pageSize=ten", http.StatusBadRequest, "invalid_argument"},
+		{http.MethodGet, "/v1/shelves/1/books?order=ORDER_NONE", http.StatusBadRequest, "invalid_argument"},
+		{http.MethodGet, "/v1/shelves/1/books?cursor=%21", http.StatusBadRequest, "invalid_argument"},
+		{http.MethodGet, "/v1/shelves/1/books?unknown=1", http.StatusBadRequest, "invalid_argument"},
+		{http.MethodGet, "/v1/shelves/1/books?filter=me", http.StatusBadRequest, "invalid_argument"},
+		{http.MethodPost, "/v1/shelves/1/books", http.StatusBadRequest, "invalid_argument"},
+		{http.MethodPost, "/v1/shelves/1/books/2:publish", http.StatusNotImplemented, "unimplemented"},
+	} {
+		body := ""
+		if test.method == http.MethodPost && !strings.HasSuffix(test.target, ":publish") {
+			body = "{not json"
+		}
+		w := serveREST(t, h, test.method, test.target, body)
+		var res struct{ Code, Message string }
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Errorf("%s %s: error %s is not JSON: %v", test.method, test.target, w.Body, err)
+			continue
+		}
+		if w.Code != test.status || res.Code != test.code {
+			t.Errorf("%s %s: status %d, code %q, want %d, %q", test.method, test.target, w.Code, res.Code, test.status, test.code)
+		}
+	}
+
+	// Requests matching no route are rejected.
+	for _, test := range []struct {
+		method, target string
+		status         int
+	}{
+		{http.MethodGet, "/v1/shelves/1/books/2/pages", http.StatusNotFound},
+		{http.MethodGet, "/v1/shelves/1:archive", http.StatusNotFound},
+		{http.MethodDelete, "/v1/shelves/1/books/2", http.StatusMethodNotAllowed},
+	} {
+		if w := serveREST(t, h, test.method, test.target, ""); w.Code != test.status {
+			t.Errorf("%s %s: status %d, want %d", test.method, test.target, w.Code, test.status)
+		}
+	}
+
+	// A single route serves only its own method and path.
+	for _, route := range NewLibraryServiceRESTRoutes(h) {
+		if route.Procedure != LibraryServiceGetFeaturedProcedure {
+			continue
+		}
+		w := httptest.NewRecorder()
+		route.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/shelves/1/books/2", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("route %s %s served another path: status %d", route.Method, route.Pattern, w.Code)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+module no_package
+
+go 1.22
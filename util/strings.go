@@ -17,7 +17,10 @@
 
 package util
 
-import "strings"
+import (
+	"strings"
+	"unicode"
+)
 
 // ToUpper will capitalize the first character
 func ToUpper(s string) string {
@@ -34,3 +37,21 @@ func ToLower(s string) string {
 	}
 	return strings.ToLower(s[:1]) + s[1:]
 }
+
+// ToSnakeCase converts a CamelCased name to snake_case, keeping runs of
+// capitals together: "HTTPGreetService" becomes "http_greet_service".
+func ToSnakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}